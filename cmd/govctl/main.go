@@ -0,0 +1,111 @@
+// govctl连接服务的治理管理接口，实时展示各rpc资源的熔断状态、QPS和错误率
+// govctl validate在上线前按规则集的JSON Schema校验JSON或YAML格式的规则文件
+// govctl simulate在上线前用合成负载离线模拟规则，检查熔断和限流何时生效
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+)
+
+// 管理接口返回的rpc资源状态
+type resourceState struct {
+	Resource      string `json:"resource"`
+	Status        int    `json:"status"`
+	Requests      int64  `json:"requests"`
+	Failures      int64  `json:"failures"`
+	DryRunRejects int64  `json:"dry_run_rejects"`
+	LastError     string `json:"last_error"`
+	TripReason    string `json:"trip_reason"`
+}
+
+var statusNames = []string{"close", "half-open", "open"}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		os.Exit(simulate(os.Args[2:]))
+	}
+
+	addr := flag.String("addr", "http://127.0.0.1:8080", "管理接口地址")
+	interval := flag.Duration("interval", 2*time.Second, "刷新间隔")
+	flag.Parse()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	last := make(map[string]resourceState)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		states, err := fetch(client, *addr+"/governance/stats")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			render(states, last, *interval)
+			last = make(map[string]resourceState, len(states))
+			for _, s := range states {
+				last[s.Resource] = s
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// 获取rpc资源状态
+func fetch(client *http.Client, url string) ([]resourceState, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("govctl: %s returned status %d", url, resp.StatusCode)
+	}
+
+	var states []resourceState
+	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
+		return nil, err
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Resource < states[j].Resource })
+
+	return states, nil
+}
+
+// 清屏并输出rpc资源状态表，QPS和错误率按与上次刷新的差值计算
+func render(states []resourceState, last map[string]resourceState, interval time.Duration) {
+	fmt.Print("\033[H\033[2J")
+	fmt.Printf("%-48s %-10s %10s %10s %12s  %s\n", "RESOURCE", "STATUS", "QPS", "ERR%", "REQUESTS", "REASON")
+	for _, s := range states {
+		prev := last[s.Resource]
+		reqs := s.Requests - prev.Requests
+		fails := s.Failures - prev.Failures
+
+		errRate := 0.0
+		if reqs > 0 {
+			errRate = float64(fails) / float64(reqs) * 100
+		}
+		status := "unknown"
+		if s.Status >= 0 && s.Status < len(statusNames) {
+			status = statusNames[s.Status]
+		}
+
+		reason := ""
+		if status != "close" {
+			reason = s.TripReason
+			if s.LastError != "" {
+				reason += ": " + s.LastError
+			}
+		}
+
+		fmt.Printf("%-48s %-10s %10.1f %10.2f %12d  %s\n",
+			s.Resource, status, float64(reqs)/interval.Seconds(), errRate, s.Requests, reason)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	governance "github.com/huago/service-governance/src"
+)
+
+// govctl simulate [-rules 规则文件] [-qps n -burst n] 负载文件，按规则对合成负载做离线模拟，输出熔断打开和开始限流的时间
+// 有资源熔断或被限流时返回1，便于在上线前检查配置能否承受预期的故障
+func simulate(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	rulesPath := fs.String("rules", "", "规则集文件，JSON或YAML格式，为空时所有资源使用默认熔断配置")
+	qps := fs.Float64("qps", 0, "每个资源的限流QPS，0表示不限流")
+	burst := fs.Int("burst", 0, "每个资源允许的突发请求数")
+	asJSON := fs.Bool("json", false, "以JSON格式输出模拟报告")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: govctl simulate [-rules rules.json] [-qps n -burst n] [-json] profile.json")
+		return 2
+	}
+
+	config := &governance.GovernanceConfig{Breaker: governance.DefaultConfig()}
+	if *qps > 0 {
+		config.Limiter = &governance.LimiterConfig{QPS: *qps, Burst: *burst}
+	}
+
+	var rules *governance.RuleSet
+	if *rulesPath != "" {
+		data, err := os.ReadFile(*rulesPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		var errs []governance.ValidationError
+		if rules, errs = validateRules(*rulesPath, data); len(errs) > 0 {
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", *rulesPath, e)
+			}
+			return 2
+		}
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	profile, err := governance.LoadSimulationProfile(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 2
+	}
+
+	start := time.Unix(0, 0)
+	report := governance.Simulate(config, rules, profile, start)
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		renderSimulation(report, start)
+	}
+
+	if report.Trips > 0 || report.RateLimited > 0 {
+		return 1
+	}
+	return 0
+}
+
+// 输出模拟报告，时间按相对模拟开始的偏移显示
+func renderSimulation(report *governance.ReplayReport, start time.Time) {
+	fmt.Printf("%-32s %10s %10s %10s %10s  %s\n", "RESOURCE", "REQUESTS", "FAILURES", "REJECTED", "LIMITED", "EVENTS")
+	for _, res := range report.Resources {
+		var events []string
+		for _, t := range res.Trips {
+			events = append(events, fmt.Sprintf("trip@%v", t.Sub(start)))
+		}
+		if !res.LimitedAt.IsZero() {
+			events = append(events, fmt.Sprintf("limit@%v", res.LimitedAt.Sub(start)))
+		}
+		if len(events) == 0 {
+			events = append(events, "-")
+		}
+		fmt.Printf("%-32s %10d %10d %10d %10d  %s\n", res.Resource, res.Requests, res.Failures, res.Rejected, res.RateLimited, strings.Join(events, " "))
+	}
+	fmt.Printf("\ntotal: %d requests, %d rejected by breaker, %d rate limited, %d trips\n",
+		report.Requests, report.Rejected, report.RateLimited, report.Trips)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	governance "github.com/huago/service-governance/src"
+)
+
+// govctl validate 规则文件...，按规则集的JSON Schema和字段之间的约束校验规则文件，有错误时返回1
+// 扩展名为.yaml或.yml的文件按YAML解析，其余按JSON解析
+func validate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: govctl validate rules.json|rules.yaml...")
+		return 2
+	}
+
+	code := 0
+	for _, file := range fs.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+
+		_, errs := validateRules(file, data)
+		for _, e := range errs {
+			fmt.Printf("%s: %s: %s\n", file, e.Path, e.Message)
+		}
+		if len(errs) > 0 {
+			code = 1
+		}
+	}
+
+	return code
+}
+
+// 按文件扩展名选择YAML或JSON格式校验规则集
+func validateRules(file string, data []byte) (*governance.RuleSet, []governance.ValidationError) {
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		return governance.ValidateRulesYAML(data)
+	}
+
+	return governance.ValidateRules(data)
+}
@@ -0,0 +1,180 @@
+package governance
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 管理接口路径
+const (
+	AdminStatsPath   = "/governance/stats"         // 查询rpc资源实时状态
+	AdminForcePath   = "/governance/force"         // 强制设置rpc资源的熔断状态
+	AdminSchemaPath  = "/governance/schema"        // 规则集的JSON Schema
+	AdminPendingPath = "/governance/rules/pending" // 查询待确认的规则变更计划
+	AdminConfirmPath = "/governance/rules/confirm" // 确认或丢弃待确认的规则版本
+	AdminDeployPath  = "/governance/deploy"        // 服务进入或退出发布模式
+)
+
+// 管理接口角色
+const (
+	RoleReader   = "reader"   // 只读，可查询状态
+	RoleOperator = "operator" // 可执行强制打开等变更操作
+)
+
+// 管理接口鉴权配置
+type AdminAuthConfig struct {
+	Tokens    map[string]string `toml:"tokens"`     // Bearer token对应的角色
+	CertRoles map[string]string `toml:"cert_roles"` // mTLS客户端证书CommonName对应的角色
+}
+
+var statusByName = map[string]BreakerStatus{
+	"close":     CloseStatus,
+	"half-open": HalfOpenStatus,
+	"open":      OpenStatus,
+}
+
+// 创建管理接口，提供rpc资源实时状态的查询和熔断状态的强制设置
+func NewAdminHandler(control *ControlPlane) http.Handler {
+	mux := http.NewServeMux()
+	// 可通过参数tag=key:value按标签过滤
+	mux.HandleFunc(AdminStatsPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, control.QueryStatsByTags(parseTagFilter(r.URL.Query()["tag"])))
+	})
+	// POST resource=rpc资源名&status=close|half-open|open&ttl=强制打开的时长如10m&operator=操作人，ttl和operator可选
+	mux.HandleFunc(AdminForcePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		status, ok := statusByName[r.FormValue("status")]
+		resource := r.FormValue("resource")
+		if !ok || resource == "" {
+			http.Error(w, "invalid resource or status", http.StatusBadRequest)
+			return
+		}
+		var ttl time.Duration
+		if v := r.FormValue("ttl"); v != "" {
+			var err error
+			if ttl, err = time.ParseDuration(v); err != nil || ttl < 0 {
+				http.Error(w, "invalid ttl", http.StatusBadRequest)
+				return
+			}
+		}
+		if err := control.ForceState(r.Context(), resource, status, ttl, r.FormValue("operator")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc(AdminSchemaPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, RuleSchema())
+	})
+	mux.HandleFunc(AdminPendingPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, control.PendingRules())
+	})
+	// POST version=规则版本&action=confirm|discard
+	mux.HandleFunc(AdminConfirmPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		version, err := strconv.ParseInt(r.FormValue("version"), 10, 64)
+		action := r.FormValue("action")
+		if err != nil || (action != "confirm" && action != "discard") {
+			http.Error(w, "invalid version or action", http.StatusBadRequest)
+			return
+		}
+		current, err := control.ConfirmRules(version, action == "confirm")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, map[string]int64{"version": current})
+	})
+
+	// POST service=服务名&duration=发布模式时长如10m，duration为0时退出发布模式
+	mux.HandleFunc(AdminDeployPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		service := r.FormValue("service")
+		duration, err := time.ParseDuration(r.FormValue("duration"))
+		if err != nil || service == "" || duration < 0 {
+			http.Error(w, "invalid service or duration", http.StatusBadRequest)
+			return
+		}
+		control.DeployMode(service, time.Now().Add(duration))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// 为管理接口增加鉴权，GET和HEAD请求需要reader或operator角色，其余请求需要operator角色
+func RequireAdminAuth(h http.Handler, config *AdminAuthConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := adminRole(r, config)
+		if role == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		readOnly := r.Method == http.MethodGet || r.Method == http.MethodHead
+		if !readOnly && role != RoleOperator {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// 获取请求的角色，优先使用Bearer token，其次使用mTLS客户端证书
+func adminRole(r *http.Request, config *AdminAuthConfig) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token := strings.TrimPrefix(auth, "Bearer ")
+		for t, role := range config.Tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				return role
+			}
+		}
+		return ""
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return config.CertRoles[r.TLS.VerifiedChains[0][0].Subject.CommonName]
+	}
+
+	return ""
+}
+
+// 解析key:value格式的标签过滤条件
+func parseTagFilter(values []string) TagFilter {
+	if len(values) == 0 {
+		return nil
+	}
+
+	filter := make(TagFilter, len(values))
+	for _, v := range values {
+		if i := strings.IndexByte(v, ':'); i > 0 {
+			filter[v[:i]] = v[i+1:]
+		}
+	}
+
+	return filter
+}
+
+// 以json格式输出v
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
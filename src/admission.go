@@ -0,0 +1,167 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// 排队队列已满时返回的错误
+	ErrQueueFull = errors.New("governance: admission queue is full")
+	// 排队超时时返回的错误
+	ErrQueueTimeout = errors.New("governance: admission queue timed out")
+)
+
+// 入站请求排队配置
+type AdmissionConfig struct {
+	MaxConcurrent int   `toml:"max_concurrent"` // 最大并发处理数
+	MaxQueue      int   `toml:"max_queue"`      // 最大排队数
+	Target        int64 `toml:"target"`         // 持续过载时的排队超时时间，单位毫秒
+	Interval      int64 `toml:"interval"`       // 队列持续非空超过此时间视为持续过载，单位毫秒
+	Timeout       int64 `toml:"timeout"`        // 短时突发时的排队超时时间，单位毫秒
+}
+
+// 排队的请求
+type admissionWaiter struct {
+	ready    chan struct{}
+	enqueue  time.Time
+	priority Priority
+}
+
+// 自适应入站排队，短时突发时按先进先出排队，队列持续非空时缩短排队超时并改为后进先出，优先处理新请求
+// 排队的请求按优先级出队，持续过载时可丢弃的请求直接被拒绝
+type AdmissionQueue struct {
+	Config *AdmissionConfig
+
+	sync.Mutex
+	inflight  int
+	waiters   []*admissionWaiter
+	lastEmpty time.Time // 队列最近一次为空的时间
+
+	queueDelay int64 // 最近一次出队请求的排队时间，单位纳秒
+	shed       int64 // 因队列已满或排队超时被拒绝的请求数
+}
+
+// 创建入站排队
+func NewAdmissionQueue(config *AdmissionConfig) *AdmissionQueue {
+	return &AdmissionQueue{
+		Config:    config,
+		lastEmpty: time.Now(),
+	}
+}
+
+// 获取处理许可，成功时返回释放许可的函数
+func (q *AdmissionQueue) Acquire(ctx context.Context) (func(), error) {
+	q.Lock()
+	now := time.Now()
+	if len(q.waiters) == 0 {
+		q.lastEmpty = now
+		if q.inflight < q.Config.MaxConcurrent {
+			q.inflight++
+			q.Unlock()
+			return q.release, nil
+		}
+	}
+	priority := PriorityFrom(ctx)
+	if len(q.waiters) >= q.Config.MaxQueue || (priority == PrioritySheddable && q.overloaded(now)) {
+		q.Unlock()
+		atomic.AddInt64(&q.shed, 1)
+		logDecision(ctx, LogAdmission, slog.LevelInfo, "request shed", "inbound", "queue full", "reject",
+			slog.String("priority", priority.String()))
+		return nil, ErrQueueFull
+	}
+
+	w := &admissionWaiter{ready: make(chan struct{}), enqueue: now, priority: priority}
+	q.waiters = append(q.waiters, w)
+	timeout := time.Duration(q.Config.Timeout) * time.Millisecond
+	if q.overloaded(now) {
+		timeout = time.Duration(q.Config.Target) * time.Millisecond
+	}
+	q.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		return q.release, nil
+	case <-timer.C:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if !q.remove(w) {
+		// 出队与超时同时发生，已获得许可
+		q.release()
+	}
+	atomic.AddInt64(&q.shed, 1)
+	logDecision(ctx, LogAdmission, slog.LevelInfo, "request shed", "inbound", "queued", "reject",
+		slog.String("error", err.Error()))
+
+	return nil, err
+}
+
+// 释放许可，将许可交给排队的请求
+func (q *AdmissionQueue) release() {
+	q.Lock()
+	defer q.Unlock()
+
+	if len(q.waiters) == 0 {
+		q.inflight--
+		return
+	}
+
+	now := time.Now()
+	// 取优先级最高的请求，同优先级下过载时取最新的请求，否则取最早的请求
+	lifo := q.overloaded(now)
+	i := 0
+	for j, v := range q.waiters {
+		if v.priority > q.waiters[i].priority || (lifo && v.priority == q.waiters[i].priority) {
+			i = j
+		}
+	}
+	w := q.waiters[i]
+	q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+	if len(q.waiters) == 0 {
+		q.lastEmpty = now
+	}
+
+	atomic.StoreInt64(&q.queueDelay, int64(now.Sub(w.enqueue)))
+	close(w.ready)
+}
+
+// 将w移出队列，w已出队时返回false
+func (q *AdmissionQueue) remove(w *admissionWaiter) bool {
+	q.Lock()
+	defer q.Unlock()
+
+	for i, v := range q.waiters {
+		if v == w {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// 判断是否持续过载，调用方需持有锁
+func (q *AdmissionQueue) overloaded(now time.Time) bool {
+	return now.Sub(q.lastEmpty) > time.Duration(q.Config.Interval)*time.Millisecond
+}
+
+// 最近一次出队请求的排队时间
+func (q *AdmissionQueue) QueueDelay() time.Duration {
+	return time.Duration(atomic.LoadInt64(&q.queueDelay))
+}
+
+// 被拒绝的请求数
+func (q *AdmissionQueue) Shed() int64 {
+	return atomic.LoadInt64(&q.shed)
+}
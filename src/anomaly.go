@@ -0,0 +1,115 @@
+package governance
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// 错误率异常预警
+type AnomalyEvent struct {
+	Time      time.Time
+	Resource  string
+	ErrorRate float64 // 本周期的错误率
+	Mean      float64 // 错误率的指数加权均值
+	ZScore    float64 // 本周期错误率偏离均值的标准差倍数
+}
+
+// 错误率异常检测配置
+type AnomalyConfig struct {
+	Alpha       float64 `toml:"alpha"`        // 指数加权系数，取值0~1，越大越侧重近期数据
+	Threshold   float64 `toml:"threshold"`    // 触发预警的z-score阈值
+	MinRequests int64   `toml:"min_requests"` // 周期内请求数低于此值时不检测
+}
+
+// 检测时使用的最小错误率标准差
+const minAnomalyStdDev = 0.01
+
+// rpc资源错误率的指数加权统计
+type ewma struct {
+	mean     float64
+	variance float64
+	samples  int
+}
+
+// 错误率异常检测器，在错误率显著偏离历史水平但熔断尚未打开时发出预警
+type AnomalyDetector struct {
+	Config    *AnomalyConfig
+	Breaker   *Breaker
+	OnAnomaly func(event AnomalyEvent)
+
+	sync.Mutex
+	last  map[string]Stat
+	stats map[string]*ewma
+}
+
+// 创建错误率异常检测器
+func NewAnomalyDetector(config *AnomalyConfig, breaker *Breaker, onAnomaly func(event AnomalyEvent)) *AnomalyDetector {
+	return &AnomalyDetector{
+		Config:    config,
+		Breaker:   breaker,
+		OnAnomaly: onAnomaly,
+		last:      make(map[string]Stat),
+		stats:     make(map[string]*ewma),
+	}
+}
+
+// 按本周期的调用统计检测异常
+func (d *AnomalyDetector) Evaluate() {
+	d.Lock()
+	defer d.Unlock()
+
+	for r, cur := range d.Breaker.Stats() {
+		prev := d.last[r]
+		d.last[r] = cur
+
+		delta := Stat{Requests: cur.Requests - prev.Requests, Failures: cur.Failures - prev.Failures}
+		if delta.Requests < d.Config.MinRequests || delta.Requests == 0 {
+			continue
+		}
+		rate := delta.ErrorRate()
+
+		e, ok := d.stats[r]
+		if !ok {
+			d.stats[r] = &ewma{mean: rate, samples: 1}
+			continue
+		}
+
+		// 先用历史均值检测，再更新均值，避免异常值拉高基线；
+		// 错误率长期稳定时标准差接近0，使用最小标准差避免微小波动触发预警
+		std := math.Max(math.Sqrt(e.variance), minAnomalyStdDev)
+		if e.samples > 1 {
+			z := (rate - e.mean) / std
+			if z >= d.Config.Threshold && d.Breaker.getStatus(r) != OpenStatus && d.OnAnomaly != nil {
+				d.OnAnomaly(AnomalyEvent{
+					Time:      time.Now(),
+					Resource:  r,
+					ErrorRate: rate,
+					Mean:      e.mean,
+					ZScore:    z,
+				})
+			}
+		}
+
+		diff := rate - e.mean
+		e.mean += d.Config.Alpha * diff
+		e.variance = (1 - d.Config.Alpha) * (e.variance + d.Config.Alpha*diff*diff)
+		e.samples++
+	}
+}
+
+// 定时检测异常，ctx结束时返回
+func (d *AnomalyDetector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Evaluate()
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package governance
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 向上游通告背压的请求头，也可作为gRPC metadata的key
+const (
+	BackpressureHeader = "x-backpressure" // 当前负载水位，0到1之间，1表示正在拒绝请求
+	RetryAfterHeader   = "retry-after"    // 建议上游重试前等待的秒数
+)
+
+// 根据上游背压调整限流时补充令牌速率的最小比例，避免完全停止调用导致无法感知上游恢复
+const minBackpressureScale = 0.1
+
+// 背压来源，返回0到1之间的负载水位
+type PressureSource interface {
+	Pressure() float64
+}
+
+// 入站排队的负载水位，按处理中和排队中的请求数占容量的比例计算
+func (q *AdmissionQueue) Pressure() float64 {
+	q.Lock()
+	defer q.Unlock()
+
+	capacity := q.Config.MaxConcurrent + q.Config.MaxQueue
+	if capacity <= 0 {
+		return 0
+	}
+	p := float64(q.inflight+len(q.waiters)) / float64(capacity)
+	if p > 1 {
+		p = 1
+	}
+
+	return p
+}
+
+// 通过set写入背压信号，set可以是http.Header.Set或写入gRPC trailer的函数，pressure为0时不写入
+func AdvertiseBackpressure(set func(key, value string), pressure float64, retryAfter time.Duration) {
+	if pressure > 0 {
+		set(BackpressureHeader, strconv.FormatFloat(pressure, 'f', 2, 64))
+	}
+	if retryAfter > 0 {
+		set(RetryAfterHeader, strconv.FormatInt(int64((retryAfter+time.Second-1)/time.Second), 10))
+	}
+}
+
+// http中间件，通过入站排队获取处理许可，在响应头中通告负载水位，被拒绝时返回503并建议上游等待
+func BackpressureHandler(next http.Handler, q *AdmissionQueue) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, err := q.Acquire(r.Context())
+		if err != nil {
+			AdvertiseBackpressure(w.Header().Set, 1, time.Duration(q.Config.Interval)*time.Millisecond)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+
+		AdvertiseBackpressure(w.Header().Set, q.Pressure(), 0)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 通过get读取上游通告的背压信号，retry-after支持秒数和HTTP日期两种格式
+func ParseBackpressure(get func(key string) string) (float64, time.Duration) {
+	var pressure float64
+	if v := get(BackpressureHeader); v != "" {
+		if p, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && p > 0 {
+			pressure = p
+		}
+	}
+	if pressure > 1 {
+		pressure = 1
+	}
+
+	var retryAfter time.Duration
+	if v := strings.TrimSpace(get(RetryAfterHeader)); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			retryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(v); err == nil {
+			retryAfter = time.Until(t)
+		}
+	}
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+
+	return pressure, retryAfter
+}
+
+// 按上游通告的背压调整调用方key的限流，负载水位越高补充令牌越慢，retry-after期间暂停放行
+func HonorBackpressure(l *Limiter, key string, get func(key string) string) {
+	pressure, retryAfter := ParseBackpressure(get)
+	scale := 1 - pressure
+	if scale < minBackpressureScale {
+		scale = minBackpressureScale
+	}
+
+	var until time.Time
+	if retryAfter > 0 {
+		until = time.Now().Add(retryAfter)
+	}
+	l.Throttle(key, scale, until)
+}
+
+// 遵循上游背压的http客户端传输层，请求前经限流器等待，响应后按背压信号调整限流
+type BackpressureTransport struct {
+	Base    http.RoundTripper              // 实际发送请求的传输层，为空时使用http.DefaultTransport
+	Limiter *Limiter                       // 客户端限流器
+	Key     func(req *http.Request) string // 请求对应的限流调用方，为空时按请求的Host限流
+}
+
+func (t *BackpressureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.Host
+	if t.Key != nil {
+		key = t.Key(req)
+	}
+	if err := t.Limiter.Wait(req.Context(), key); err != nil {
+		return nil, err
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	HonorBackpressure(t.Limiter, key, resp.Header.Get)
+
+	return resp, nil
+}
@@ -1,15 +1,92 @@
 package governance
 
 import (
+	"context"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // 熔断器配置
 type Config struct {
-	FailThreshold int   `toml:"fail_threshold"` // 失败阈值
-	SuccThreshold int   `toml:"succ_threshold"` // 成功阈值
-	OpenTimeout   int64 `toml:"open_timeout"`   // 熔断状态置为打开状态的时间阈值，超过此时间将状态置为半打开状态
+	FailThreshold int  `toml:"fail_threshold"` // 失败阈值
+	SuccThreshold int  `toml:"succ_threshold"` // 成功阈值
+	RePanic       bool `toml:"re_panic"`       // 调用发生panic时，计为失败后是否重新panic
+	// 熔断状态置为打开状态的时间阈值，超过此时间将状态置为半打开状态，为0时使用默认值30秒
+	// 配置文件和JSON中写为"30s"、"2m"等字符串；不带单位的整数在解码时按秒处理，已废弃
+	OpenTimeout time.Duration `toml:"open_timeout"`
+	// 熔断打开时间的上限，大于OpenTimeout时，半打开状态下再次打开熔断的打开时间按指数增长，直到关闭熔断后重置
+	MaxOpenTimeout time.Duration `toml:"max_open_timeout"`
+	// 最小请求数，关闭状态下统计窗口内的请求数未达到此值时不会打开熔断
+	MinRequestVolume int `toml:"min_request_volume"`
+	// 关闭状态下失败数和请求数的统计窗口，窗口结束时清零，为0时使用默认值10秒
+	StatWindow time.Duration `toml:"stat_window"`
+	// 是否关闭熔断，关闭后仍记录熔断状态但不拒绝调用，为空时继承低层级的配置，高层级可设为false重新开启
+	Disabled *bool `toml:"disabled"`
+	// 是否为试运行模式，试运行模式下只记录会被拒绝的调用而不拒绝，用于上线前验证阈值
+	DryRun bool `toml:"dry_run"`
+	// 后台定时任务的执行间隔，单位毫秒，为0时使用默认值5秒
+	TickInterval int64 `toml:"tick_interval"`
+	// 后台定时任务执行间隔的随机抖动比例，取值0~1，避免集群内实例同时执行
+	TickJitter float64 `toml:"tick_jitter"`
+	// rpc资源的标签，如team、tier、criticality，用于查询时过滤
+	Tags map[string]string `toml:"tags"`
+	// 经过此rpc资源的请求的延迟预算，单位毫秒，0表示不限制
+	LatencyBudget int64 `toml:"latency_budget"`
+	// 调用超过此时间仍未返回时由看门狗记录调用栈并计为失败，单位毫秒，0表示不检测
+	WatchdogTimeout int64 `toml:"watchdog_timeout"`
+	// 各类错误计入失败阈值的权重，key为ErrorClassOf返回的错误类别，如timeout=2、5xx=1、connection_refused=3，未列出的错误权重为1
+	ErrorWeights map[string]float64 `toml:"error_weights"`
+	// 熔断由半打开恢复为关闭后的宽限期，期间的失败只记录日志不计入失败阈值，吸收熔断期间排队或执行中的请求返回的残留错误
+	RecoveryGrace time.Duration `toml:"recovery_grace"`
+}
+
+// 后台定时任务的默认执行间隔
+const defaultTickInterval = 5 * time.Second
+
+// 熔断打开时间的默认值
+const defaultOpenTimeout = 30 * time.Second
+
+// 关闭状态下统计窗口的默认值
+const defaultStatWindow = 10 * time.Second
+
+// 熔断打开时间及其上限，OpenTimeout未设置时使用默认值
+func (config *Config) openTimeouts() (time.Duration, time.Duration) {
+	timeout := config.OpenTimeout
+	if timeout <= 0 {
+		timeout = defaultOpenTimeout
+	}
+
+	return timeout, config.MaxOpenTimeout
+}
+
+// 关闭状态下的统计窗口
+func (config *Config) statWindow() time.Duration {
+	if config.StatWindow <= 0 {
+		return defaultStatWindow
+	}
+
+	return config.StatWindow
+}
+
+// 是否关闭熔断
+func (config *Config) disabled() bool {
+	return config.Disabled != nil && *config.Disabled
+}
+
+// 返回v的指针，用于设置Config.Disabled等可继承的布尔配置
+func Bool(v bool) *bool {
+	return &v
+}
+
+// 后台定时任务的执行间隔
+func (config *Config) tickInterval() time.Duration {
+	if config.TickInterval <= 0 {
+		return defaultTickInterval
+	}
+
+	return time.Duration(config.TickInterval) * time.Millisecond
 }
 
 // 熔断状态
@@ -26,22 +103,64 @@ type RPC struct {
 	Status    BreakerStatus // 当前熔断状态
 	FailCount int           // 失败次数
 	SuccCount int           // 成功次数
-	OpenTime  int64         // 熔断状态置为打开时的时间
+	OpenTime  int64         // 熔断状态置为打开时的unix时间，只用于展示，打开时长按openedAt计算
+	ReqCount  int           // 关闭状态下的请求次数
+
+	OpenTimeout time.Duration // 本次熔断打开的持续时间
+	failScore   float64       // 关闭状态下按错误类别加权的失败数，与失败阈值比较
+	closedAt    time.Time     // 由半打开恢复为关闭的时间，用于计算恢复后的宽限期
+	windowStart time.Time     // 关闭状态下当前统计窗口的开始时间
+	ReopenCount int           // 半打开状态下连续重新打开熔断的次数
+	openedAt    time.Time     // 熔断状态置为打开时的时间，带单调时钟读数，不受系统时间跳变影响
 }
 
+// 半打开状态下判断请求是否可以作为探测请求，返回false的请求将被拒绝
+type ProbeSelector func(ctx context.Context, r string) bool
+
 // 熔断器
 type Breaker struct {
 	Config *Config
-	sync.Mutex
-	R map[string]*RPC
+	sync.RWMutex
+	R             map[string]*RPC
+	Configs       map[string]*Config // rpc资源级别的配置，未配置的rpc资源使用Config
+	tuned         map[string]*Config // 自动调整的配置，覆盖Configs中的同名字段，见SetTunedConfig
+	ProbeSelector ProbeSelector      // 半打开状态下的探测请求选择策略，为空时所有请求均可作为探测请求
+	disabled      map[string]bool    // 运行时关闭熔断的rpc资源
+	allDisabled   int32              // 是否运行时关闭所有rpc资源的熔断
+	stats         map[string]*Stat   // rpc资源的累计调用统计
+	lastTick      int64              // 后台定时任务最近一次执行的时间，单位纳秒
+	lastTickMono  int64              // 后台定时任务最近一次执行时相对monoEpoch的单调时间，单位纳秒
+	lazy          bool               // 是否在判断调用时才将熔断状态由打开置为半打开
+	Classifier    Classifier         // 默认的调用结果分类器，为空时返回错误即计为失败
+	classifiers   map[string]Classifier
+	listeners     []StateListener        // 熔断状态变更的监听器
+	events        chan StateChange       // 待分发的熔断状态变更
+	Guard         *CardinalityGuard      // rpc资源数的基数保护，为空时不限制
+	now           func() time.Time       // 时钟，回放录制的流量时替换为录制的时间
+	effective     *sync.Map              // rpc资源按层级合并后的配置缓存
+	Watchdog      *Watchdog              // 超时未返回调用的看门狗，为空时不检测
+	ErrorClassOf  func(err error) string // 错误类别的判断，用于按ErrorWeights加权，为空时使用DefaultErrorClass
+	inflight      sync.Map               // rpc资源正在执行的调用数，即阻塞在调用中的goroutine数，值为*int64
+	windows       sync.Map               // rpc资源最近1分钟的滑动窗口统计，值为*windowStats
+	OnReject      func(r, reason string) // 调用被拒绝时调用，reason为拒绝原因，需在开始调用前设置
+	shadows       sync.Map               // rpc资源的影子熔断策略，值为*shadow
+	deploys       map[string]time.Time   // 处于发布模式的服务及发布模式的结束时间
+	DeployConfig  *DeployModeConfig      // 发布模式配置，为空时使用默认值
+	gen           uint64                 // 每次释放写锁或熔断状态变更时加1，用于判断allow使用的状态快照是否失效
+	snapshots     sync.Map               // rpc资源的状态快照，值为*allowSnapshot
+}
+
+// 单调时间的起点，时长均按相对此时间的单调时钟读数计算
+var monoEpoch = time.Now()
+
+// 相对monoEpoch的单调时间，单位纳秒
+func monoNow() int64 {
+	return int64(time.Since(monoEpoch))
 }
 
 // 初始化熔断器
 func InitBreaker(config *Config) *Breaker {
-	breaker := &Breaker{
-		Config: config,
-		R:      make(map[string]*RPC),
-	}
+	breaker := newBreaker(config)
 
 	// 启动定时器，定时将rpc资源的熔断状态从打开置为半打开
 	go autoHalfOpen(breaker)
@@ -49,31 +168,155 @@ func InitBreaker(config *Config) *Breaker {
 	return breaker
 }
 
+// 初始化不启动后台goroutine的熔断器，在判断调用时根据打开时间将熔断状态由打开置为半打开
+func InitLazyBreaker(config *Config) *Breaker {
+	breaker := newBreaker(config)
+	breaker.lazy = true
+
+	return breaker
+}
+
+func newBreaker(config *Config) *Breaker {
+	return &Breaker{
+		Config:      config,
+		R:           make(map[string]*RPC),
+		Configs:     make(map[string]*Config),
+		disabled:    make(map[string]bool),
+		stats:       make(map[string]*Stat),
+		classifiers: make(map[string]Classifier),
+		now:         time.Now,
+		effective:   &sync.Map{},
+	}
+}
+
 // 自动将rpc资源的熔断状态由打开置为半打开
 func autoHalfOpen(breaker *Breaker) {
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+	interval := breaker.Config.tickInterval()
+	timer := time.NewTimer(jitter(interval, breaker.Config.TickJitter))
+	defer timer.Stop()
+	// 启动时即记录，就绪检查据此判断后台定时任务已运行
+	breaker.markTick()
 
-	nowTime := time.Now().Unix()
 	for {
 		select {
-		case <-ticker.C:
-			for r, v := range breaker.R {
-				if v.Status == OpenStatus && v.OpenTime+breaker.Config.OpenTimeout > nowTime {
-					breaker.Lock()
-					breaker.R[r] = &RPC{
-						Status:    HalfOpenStatus,
-						FailCount: 0,
-						SuccCount: 0,
-						OpenTime:  0,
-					}
-					breaker.Unlock()
-				}
-			}
+		case <-timer.C:
+			timer.Reset(jitter(interval, breaker.Config.TickJitter))
+			breaker.markTick()
+			breaker.tick(time.Now())
 		}
 	}
 }
 
+// 记录后台定时任务的执行时间
+func (breaker *Breaker) markTick() {
+	atomic.StoreInt64(&breaker.lastTick, time.Now().UnixNano())
+	atomic.StoreInt64(&breaker.lastTickMono, monoNow())
+}
+
+// 将打开时间已到期的rpc资源置为半打开
+// 先在读锁下收集到期的资源快照，再逐个加写锁复查后转换，避免遍历map时与setFail等并发写冲突，也不长时间持有写锁
+func (breaker *Breaker) tick(nowTime time.Time) {
+	breaker.RLock()
+	var expired []string
+	for r, v := range breaker.R {
+		if v.openExpired(nowTime) {
+			expired = append(expired, r)
+		}
+	}
+	breaker.RUnlock()
+
+	for _, r := range expired {
+		breaker.Lock()
+		// 收集快照后状态可能已被其他goroutine改变
+		if v, ok := breaker.R[r]; ok && v.openExpired(nowTime) {
+			setHalfOpenStatus(v)
+			breaker.emit(r, OpenStatus, HalfOpenStatus, ReasonOpenTimeout)
+		}
+		breaker.Unlock()
+	}
+}
+
+// 设置rpc资源r的配置，config为空时恢复使用默认配置，r可以是服务名或只包含部分维度的资源标识，见ResolveEffectiveConfig
+func (breaker *Breaker) SetConfig(r string, config *Config) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	defer breaker.invalidateConfigs()
+	if config == nil {
+		delete(breaker.Configs, r)
+		return
+	}
+	breaker.Configs[r] = config
+}
+
+// 获取rpc资源r单独设置的配置，未单独设置时返回空
+func (breaker *Breaker) GetConfig(r string) *Config {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	return breaker.Configs[r]
+}
+
+// 关闭rpc资源r的熔断，仍记录熔断状态但不拒绝调用
+func (breaker *Breaker) Disable(r string) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	breaker.disabled[r] = true
+}
+
+// 恢复rpc资源r的熔断
+func (breaker *Breaker) Enable(r string) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	delete(breaker.disabled, r)
+}
+
+// 关闭所有rpc资源的熔断，仍记录熔断状态但不拒绝调用，用于预发环境或故障处理时的全局开关
+func (breaker *Breaker) DisableAll() {
+	atomic.StoreInt32(&breaker.allDisabled, 1)
+}
+
+// 恢复所有rpc资源的熔断，由Disable单独关闭的rpc资源和配置关闭的rpc资源仍保持关闭
+func (breaker *Breaker) EnableAll() {
+	atomic.StoreInt32(&breaker.allDisabled, 0)
+}
+
+// 是否关闭了所有rpc资源的熔断
+func (breaker *Breaker) AllDisabled() bool {
+	return atomic.LoadInt32(&breaker.allDisabled) == 1
+}
+
+// 判断熔断打开时间是否已超过打开时长，按单调时钟计算，系统时间跳变不会提前或推迟转为半打开
+func (rpc *RPC) openExpired(nowTime time.Time) bool {
+	if rpc.Status != OpenStatus {
+		return false
+	}
+
+	return !nowTime.Before(rpc.openDeadline())
+}
+
+// 熔断打开的到期时间
+func (rpc *RPC) openDeadline() time.Time {
+	openedAt := rpc.openedAt
+	if openedAt.IsZero() {
+		// 直接设置OpenTime的rpc资源没有单调时钟读数，按unix时间计算
+		openedAt = time.Unix(rpc.OpenTime, 0)
+	}
+
+	return openedAt.Add(rpc.OpenTimeout)
+}
+
+// 关闭状态下当前统计窗口已结束时清零失败数和请求数，使最小请求数和失败阈值只按最近一个窗口内的调用判断
+func (rpc *RPC) rollWindow(config *Config, now time.Time) {
+	if now.Sub(rpc.windowStart) < config.statWindow() {
+		return
+	}
+	rpc.FailCount, rpc.failScore, rpc.ReqCount = 0, 0, 0
+	rpc.windowStart = now
+}
+
 func (rpc *RPC) isHalfOpen() bool {
 	return rpc.Status == HalfOpenStatus
 }
@@ -86,50 +329,163 @@ func (rpc *RPC) isClose() bool {
 	return rpc.Status == CloseStatus
 }
 
+// 释放写锁，并使allow使用的状态快照失效
+func (breaker *Breaker) Unlock() {
+	atomic.AddUint64(&breaker.gen, 1)
+	breaker.RWMutex.Unlock()
+}
+
+// 释放写锁但不使状态快照失效，只用于更新调用计数，熔断状态变更时由emit使快照失效
+func (breaker *Breaker) unlockCounters() {
+	breaker.RWMutex.Unlock()
+}
+
 // 获取rpc资源熔断状态
 func (breaker *Breaker) getStatus(r string) BreakerStatus {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	return breaker.status(r)
+}
+
+// 获取rpc资源熔断状态，调用方需持有锁
+func (breaker *Breaker) status(r string) BreakerStatus {
 	if v, ok := breaker.R[r]; ok {
+		if breaker.lazy && v.openExpired(breaker.now()) {
+			return HalfOpenStatus
+		}
 		return v.Status
 	}
 
 	return CloseStatus
 }
 
-// 设置rpc资源的熔断状态为打开
-func setOpenStatus(rpc *RPC) {
-	rpc = &RPC{
-		Status:    OpenStatus,
-		FailCount: 0,
-		SuccCount: 0,
-		OpenTime:  time.Now().Unix(),
+// 强制设置rpc资源r的熔断状态
+func (breaker *Breaker) ForceState(r string, status BreakerStatus) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	v := breaker.ensure(r)
+	from := v.Status
+	if status == OpenStatus {
+		*v = RPC{}
+		setOpenStatus(breaker.configOf(r), v, breaker.now())
+		breaker.trip(r, from, TripForced)
+		return
+	}
+	*v = RPC{Status: status}
+	breaker.emit(r, from, status, ReasonForced)
+}
+
+// 强制打开rpc资源r的熔断，ttl后按正常流程转为半打开，避免强制状态被遗忘
+func (breaker *Breaker) ForceOpenFor(r string, ttl time.Duration) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	v := breaker.ensure(r)
+	from := v.Status
+	*v = RPC{}
+	setOpenStatus(breaker.configOf(r), v, breaker.now())
+	v.OpenTimeout = ttl
+	breaker.trip(r, from, TripForced)
+}
+
+// 将打开时间已到期的rpc资源r的熔断状态置为半打开，状态变化时由emit使快照失效
+func (breaker *Breaker) lazyHalfOpen(r string) {
+	breaker.Lock()
+	defer breaker.unlockCounters()
+
+	if v, ok := breaker.R[r]; ok && v.openExpired(breaker.now()) {
+		setHalfOpenStatus(v)
+		breaker.emit(r, OpenStatus, HalfOpenStatus, ReasonOpenTimeout)
+	}
+}
+
+// 设置rpc资源的熔断状态为半打开
+func setHalfOpenStatus(rpc *RPC) {
+	*rpc = RPC{
+		Status:      HalfOpenStatus,
+		FailCount:   0,
+		SuccCount:   0,
+		OpenTime:    0,
+		ReopenCount: rpc.ReopenCount,
 	}
 }
 
+// 设置rpc资源的熔断状态为打开，nowTime为当前时间
+func setOpenStatus(config *Config, rpc *RPC, nowTime time.Time) {
+	reopenCount := 0
+	if rpc.isHalfOpen() {
+		reopenCount = rpc.ReopenCount + 1
+	}
+
+	*rpc = RPC{
+		Status:      OpenStatus,
+		FailCount:   0,
+		SuccCount:   0,
+		OpenTime:    nowTime.Unix(),
+		OpenTimeout: openTimeout(config, reopenCount),
+		ReopenCount: reopenCount,
+		openedAt:    nowTime,
+	}
+}
+
+// 计算连续重新打开reopenCount次后的熔断打开时间
+func openTimeout(config *Config, reopenCount int) time.Duration {
+	base, max := config.openTimeouts()
+	timeout := base
+	for i := 0; i < reopenCount && timeout < max; i++ {
+		timeout *= 2
+	}
+	if max > base && timeout > max {
+		timeout = max
+	}
+
+	return timeout
+}
+
 // 调用rpc资源r失败
-func (breaker *Breaker) setFail(r string) {
+func (breaker *Breaker) setFail(r string, err error) {
 	breaker.Lock()
-	defer breaker.Unlock()
+	defer breaker.unlockCounters()
 
+	breaker.record(r, err)
+	config := breaker.configOf(r)
+	weight := breaker.errorWeight(config, err)
 	if v, ok := breaker.R[r]; ok {
 		/*
 		 * 1.rpc资源的熔断状态处于半打开时，只要有失败，就置为打开
 		 * 2.rpc资源的熔断状态处于关闭时，当失败次数超过阈值，则置为打开
 		 */
 		if v.isHalfOpen() {
-			setOpenStatus(breaker.R[r])
+			setOpenStatus(config, breaker.R[r], breaker.now())
+			breaker.trip(r, HalfOpenStatus, TripHalfOpenFailure)
 		} else if v.isClose() {
+			if grace := config.RecoveryGrace; !v.closedAt.IsZero() && breaker.now().Sub(v.closedAt) < grace {
+				logDecision(context.Background(), LogBreaker, slog.LevelInfo, "failure ignored during recovery grace", r, "close", "ignore",
+					slog.Any("error", err), slog.Duration("since_close", breaker.now().Sub(v.closedAt)))
+				return
+			}
+			v.rollWindow(config, breaker.now())
 			v.FailCount++
-			if v.FailCount >= breaker.Config.FailThreshold {
-				setOpenStatus(breaker.R[r])
+			v.failScore += weight
+			v.ReqCount++
+			if v.failScore >= float64(config.FailThreshold) && v.ReqCount >= config.MinRequestVolume {
+				setOpenStatus(config, breaker.R[r], breaker.now())
+				breaker.trip(r, CloseStatus, TripFailThreshold)
 			}
 		}
 	} else {
 		breaker.R[r] = &RPC{}
-		// 当失败阈值为1时，直接将rpc资源的熔断状态置为打开
-		if breaker.Config.FailThreshold == 1 {
-			setOpenStatus(breaker.R[r])
+		// 当失败的权重达到失败阈值且无最小请求数限制时，直接将rpc资源的熔断状态置为打开
+		if weight >= float64(config.FailThreshold) && config.MinRequestVolume <= 1 {
+			setOpenStatus(config, breaker.R[r], breaker.now())
+			breaker.trip(r, CloseStatus, TripFailThreshold)
 		} else {
 			breaker.R[r].FailCount = 1
+			breaker.R[r].failScore = weight
+			breaker.R[r].ReqCount = 1
+			breaker.R[r].windowStart = breaker.now()
 		}
 	}
 }
@@ -137,22 +493,32 @@ func (breaker *Breaker) setFail(r string) {
 // 调用rpc资源r成功
 func (breaker *Breaker) setSucc(r string) {
 	breaker.Lock()
-	defer breaker.Unlock()
+	defer breaker.unlockCounters()
 
+	breaker.record(r, nil)
 	/*
-	 * 当rpc资源的熔断状态处于半打开时，若成功次数超过成功阈值，则置为关闭
+	 * 1.当rpc资源的熔断状态处于半打开时，若成功次数超过成功阈值，则置为关闭
+	 * 2.当rpc资源的熔断状态处于关闭且配置了最小请求数时，记录请求次数
 	 */
 	if v, ok := breaker.R[r]; ok {
-		if v.isHalfOpen() {
+		if v.isClose() {
+			v.rollWindow(breaker.configOf(r), breaker.now())
+			v.ReqCount++
+		} else if v.isHalfOpen() {
 			v.SuccCount++
-			if v.SuccCount >= breaker.Config.SuccThreshold {
-				breaker.R[r] = &RPC{
+			if v.SuccCount >= breaker.configOf(r).SuccThreshold {
+				// 关闭熔断时重置打开时间的指数增长
+				*v = RPC{
 					Status:    CloseStatus,
 					FailCount: 0,
 					SuccCount: 0,
 					OpenTime:  0,
+					closedAt:  breaker.now(),
 				}
+				breaker.emit(r, HalfOpenStatus, CloseStatus, ReasonSuccThreshold)
 			}
 		}
+	} else if breaker.configOf(r).MinRequestVolume > 0 {
+		breaker.R[r] = &RPC{ReqCount: 1, windowStart: breaker.now()}
 	}
 }
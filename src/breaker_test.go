@@ -0,0 +1,99 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 在-race下运行，检查后台定时任务与调用、配置变更和强制设置状态并发时没有数据竞争，且状态转换保持一致
+func TestConcurrentTransitions(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 2
+	config.SuccThreshold = 1
+	config.OpenTimeout = time.Millisecond
+	breaker := newBreaker(&config)
+	breaker.Subscribe(func(StateChange) {})
+
+	errFail := errors.New("fail")
+	resources := []string{"a", "b", "c", "d"}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ctx.Err() == nil; i++ {
+				f(i)
+			}
+		}()
+	}
+
+	run(func(int) {
+		breaker.tick(time.Now().Add(time.Second))
+	})
+	for w := 0; w < 4; w++ {
+		w := w
+		run(func(i int) {
+			r := resources[(i+w)%len(resources)]
+			breaker.Execute(ctx, r, func(ctx context.Context) error {
+				if i%3 == 0 {
+					return errFail
+				}
+				return nil
+			})
+		})
+	}
+	run(func(i int) {
+		r := resources[i%len(resources)]
+		if i%2 == 0 {
+			breaker.SetConfig(r, &Config{FailThreshold: 1 + i%3})
+		} else {
+			breaker.SetConfig(r, nil)
+		}
+	})
+	run(func(i int) {
+		r := resources[i%len(resources)]
+		breaker.ForceState(r, BreakerStatus(i%3))
+		breaker.Available(r)
+		breaker.State(r)
+	})
+	run(func(int) {
+		breaker.DebugInfo()
+		breaker.Stats()
+		breaker.InFlight()
+	})
+	wg.Wait()
+
+	for _, r := range resources {
+		if s := breaker.State(r); s != CloseStatus && s != OpenStatus && s != HalfOpenStatus {
+			t.Errorf("resource %s in invalid state %d", r, s)
+		}
+	}
+	if n := fmt.Sprint(breaker.InFlight()); n != "map[a:0 b:0 c:0 d:0]" {
+		t.Errorf("in-flight calls left after all calls returned: %s", n)
+	}
+}
+
+// 注册时只设置选项中的字段，服务级配置仍然生效
+func TestRegisterKeepsLowerLayers(t *testing.T) {
+	config := DefaultConfig()
+	breaker := newBreaker(&config)
+	breaker.SetConfig("svc", &Config{FailThreshold: 20})
+	r := ResourceKey{Service: "svc", Method: "m"}.Encode()
+	breaker.Register(r, WithTags(map[string]string{"team": "core"}))
+	breaker.Register(r, WithSuccThreshold(3))
+
+	effective := breaker.ResolveEffectiveConfig(r)
+	if effective.Config.FailThreshold != 20 || effective.Sources["fail_threshold"] != LayerService {
+		t.Fatalf("fail_threshold = %d from %s, want 20 from %s", effective.Config.FailThreshold, effective.Sources["fail_threshold"], LayerService)
+	}
+	if effective.Config.Tags["team"] != "core" || effective.Config.SuccThreshold != 3 {
+		t.Fatalf("config = %+v, want tags and succ_threshold from Register", effective.Config)
+	}
+}
@@ -0,0 +1,62 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// 延迟预算耗尽时跳过可选调用返回的错误
+var ErrBudgetExhausted = errors.New("governance: latency budget exhausted")
+
+// 请求的延迟预算，累计下游调用的耗时
+type LatencyBudget struct {
+	total time.Duration
+	spent int64 // 已消耗的时间，单位纳秒
+}
+
+type budgetKey struct{}
+
+// 为请求设置延迟预算
+func WithLatencyBudget(ctx context.Context, total time.Duration) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &LatencyBudget{total: total})
+}
+
+// 按rpc资源r配置的延迟预算为请求设置预算，未配置时返回原ctx
+func (breaker *Breaker) WithLatencyBudget(ctx context.Context, r string) context.Context {
+	breaker.RLock()
+	budget := breaker.configOf(r).LatencyBudget
+	breaker.RUnlock()
+
+	if budget <= 0 {
+		return ctx
+	}
+
+	return WithLatencyBudget(ctx, time.Duration(budget)*time.Millisecond)
+}
+
+// 获取请求的延迟预算
+func BudgetFrom(ctx context.Context) (*LatencyBudget, bool) {
+	b, ok := ctx.Value(budgetKey{}).(*LatencyBudget)
+	return b, ok
+}
+
+// 记录一次下游调用的耗时
+func (b *LatencyBudget) Observe(d time.Duration) {
+	atomic.AddInt64(&b.spent, int64(d))
+}
+
+// 剩余的延迟预算
+func (b *LatencyBudget) Remaining() time.Duration {
+	return b.total - time.Duration(atomic.LoadInt64(&b.spent))
+}
+
+// 调用可选的下游rpc资源r，请求的延迟预算已耗尽时直接跳过
+func (breaker *Breaker) ExecuteOptional(ctx context.Context, r string, fn func(ctx context.Context) error) error {
+	if b, ok := BudgetFrom(ctx); ok && b.Remaining() <= 0 {
+		return ErrBudgetExhausted
+	}
+
+	return breaker.Execute(ctx, r, fn)
+}
@@ -0,0 +1,96 @@
+package governance
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rpc资源的容量估计
+type CapacityEstimate struct {
+	Resource   string  `json:"resource"`
+	MaxQPS     float64 `json:"max_qps"`     // 健康状态下观测到的最大QPS
+	CurrentQPS float64 `json:"current_qps"` // 最近一个周期的QPS
+	Healthy    bool    `json:"healthy"`     // 最近一个周期是否健康
+}
+
+// 容量估计器，以错误率低于MaxErrorRate的周期中观测到的最大QPS作为可持续容量
+type CapacityEstimator struct {
+	Breaker      *Breaker
+	MaxErrorRate float64 // 健康周期的最大错误率
+
+	sync.Mutex
+	last      map[string]Stat
+	lastTime  time.Time
+	estimates map[string]*CapacityEstimate
+}
+
+// 创建容量估计器
+func NewCapacityEstimator(breaker *Breaker, maxErrorRate float64) *CapacityEstimator {
+	return &CapacityEstimator{
+		Breaker:      breaker,
+		MaxErrorRate: maxErrorRate,
+		last:         make(map[string]Stat),
+		lastTime:     time.Now(),
+		estimates:    make(map[string]*CapacityEstimate),
+	}
+}
+
+// 按上次评估以来的调用统计更新容量估计
+func (c *CapacityEstimator) Evaluate() {
+	c.Lock()
+	defer c.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(c.lastTime).Seconds()
+	c.lastTime = now
+	if elapsed <= 0 {
+		return
+	}
+
+	for r, cur := range c.Breaker.Stats() {
+		prev := c.last[r]
+		c.last[r] = cur
+
+		delta := Stat{Requests: cur.Requests - prev.Requests, Failures: cur.Failures - prev.Failures}
+		e, ok := c.estimates[r]
+		if !ok {
+			e = &CapacityEstimate{Resource: r}
+			c.estimates[r] = e
+		}
+
+		e.CurrentQPS = float64(delta.Requests) / elapsed
+		e.Healthy = delta.ErrorRate() <= c.MaxErrorRate && c.Breaker.getStatus(r) == CloseStatus
+		if e.Healthy && e.CurrentQPS > e.MaxQPS {
+			e.MaxQPS = e.CurrentQPS
+		}
+	}
+}
+
+// 所有rpc资源的容量估计
+func (c *CapacityEstimator) Estimates() map[string]CapacityEstimate {
+	c.Lock()
+	defer c.Unlock()
+
+	estimates := make(map[string]CapacityEstimate, len(c.estimates))
+	for r, e := range c.estimates {
+		estimates[r] = *e
+	}
+
+	return estimates
+}
+
+// 定时更新容量估计，ctx结束时返回
+func (c *CapacityEstimator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Evaluate()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package governance
+
+import (
+	"sync"
+)
+
+// 超过基数上限的资源或标签值合并到此名称下
+const OverflowResource = "other"
+
+// 最多记录的被合并key数，超过后不再告警，只计数，避免被合并的key无限增长
+const maxOverflowTracked = 1024
+
+// 基数保护，限制不同资源名或标签值的数量，超出部分合并为OverflowResource
+type CardinalityGuard struct {
+	Max        int              // 允许的最大基数
+	OnOverflow func(key string) // 首次出现被合并的key时的告警，为空时不告警，最多告警maxOverflowTracked个key
+
+	sync.RWMutex
+	seen       map[string]struct{}
+	overflowed map[string]struct{} // 已告警的被合并key
+	untracked  int                 // 超过记录上限后被合并的次数
+}
+
+// 创建基数保护
+func NewCardinalityGuard(max int, onOverflow func(key string)) *CardinalityGuard {
+	return &CardinalityGuard{
+		Max:        max,
+		OnOverflow: onOverflow,
+		seen:       make(map[string]struct{}),
+		overflowed: make(map[string]struct{}),
+	}
+}
+
+// 返回key实际使用的名称，超过基数上限的新key返回OverflowResource
+func (g *CardinalityGuard) Guard(key string) string {
+	g.RLock()
+	_, ok := g.seen[key]
+	g.RUnlock()
+	if ok {
+		return key
+	}
+
+	g.Lock()
+	if _, ok := g.seen[key]; ok {
+		g.Unlock()
+		return key
+	}
+	if len(g.seen) < g.Max {
+		g.seen[key] = struct{}{}
+		g.Unlock()
+		return key
+	}
+
+	_, warned := g.overflowed[key]
+	if !warned {
+		if len(g.overflowed) < maxOverflowTracked {
+			g.overflowed[key] = struct{}{}
+		} else {
+			g.untracked++
+			warned = true
+		}
+	}
+	g.Unlock()
+
+	if !warned && g.OnOverflow != nil {
+		g.OnOverflow(key)
+	}
+
+	return OverflowResource
+}
+
+// 返回key实际使用的名称，与Guard不同，未出现过的key不计入基数
+func (g *CardinalityGuard) lookup(key string) string {
+	g.RLock()
+	defer g.RUnlock()
+
+	if _, ok := g.seen[key]; ok || len(g.seen) < g.Max {
+		return key
+	}
+
+	return OverflowResource
+}
+
+// 被合并的不同key的数量，超过maxOverflowTracked后按被合并的次数累加，结果偏大
+func (g *CardinalityGuard) Overflowed() int {
+	g.RLock()
+	defer g.RUnlock()
+
+	return len(g.overflowed) + g.untracked
+}
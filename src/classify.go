@@ -0,0 +1,176 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+)
+
+// 调用结果分类
+type Outcome int
+
+const (
+	OutcomeSuccess Outcome = iota // 计为成功
+	OutcomeFailure                // 计为失败
+	OutcomeIgnore                 // 不计入熔断统计
+)
+
+// 调用结果分类器，根据调用返回的错误判断调用结果
+type Classifier func(err error) Outcome
+
+// 默认分类器，返回错误即计为失败
+func DefaultClassifier(err error) Outcome {
+	if err != nil {
+		return OutcomeFailure
+	}
+
+	return OutcomeSuccess
+}
+
+// 携带http状态码的错误
+type HTTPStatusError struct {
+	Code int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return "governance: http status " + strconv.Itoa(e.Code) + " " + http.StatusText(e.Code)
+}
+
+// 按http状态码分类，5xx和429计为失败，其余计为成功，overrides中的状态码优先
+func ClassifyHTTPStatus(code int, overrides map[int]Outcome) Outcome {
+	if o, ok := overrides[code]; ok {
+		return o
+	}
+	if code >= 500 || code == http.StatusTooManyRequests {
+		return OutcomeFailure
+	}
+
+	return OutcomeSuccess
+}
+
+// http分类器，错误为HTTPStatusError时按状态码分类，其余错误计为失败
+func HTTPClassifier(overrides map[int]Outcome) Classifier {
+	return func(err error) Outcome {
+		var se *HTTPStatusError
+		if errors.As(err, &se) {
+			return ClassifyHTTPStatus(se.Code, overrides)
+		}
+
+		return DefaultClassifier(err)
+	}
+}
+
+// gRPC状态码
+const (
+	grpcCanceled          = 1
+	grpcUnknown           = 2
+	grpcDeadlineExceeded  = 4
+	grpcResourceExhausted = 8
+	grpcAborted           = 10
+	grpcInternal          = 13
+	grpcUnavailable       = 14
+	grpcDataLoss          = 15
+)
+
+// 按gRPC状态码分类，服务端故障类状态码计为失败，Canceled不计入统计，其余计为成功，overrides中的状态码优先
+func ClassifyGRPCCode(code uint32, overrides map[uint32]Outcome) Outcome {
+	if o, ok := overrides[code]; ok {
+		return o
+	}
+
+	switch code {
+	case grpcCanceled:
+		return OutcomeIgnore
+	case grpcUnknown, grpcDeadlineExceeded, grpcResourceExhausted, grpcAborted,
+		grpcInternal, grpcUnavailable, grpcDataLoss:
+		return OutcomeFailure
+	}
+
+	return OutcomeSuccess
+}
+
+// gRPC分类器，codeOf从错误中提取gRPC状态码，通常基于status.FromError实现
+func GRPCClassifier(codeOf func(err error) (uint32, bool), overrides map[uint32]Outcome) Classifier {
+	return func(err error) Outcome {
+		if err == nil {
+			return OutcomeSuccess
+		}
+		if code, ok := codeOf(err); ok {
+			return ClassifyGRPCCode(code, overrides)
+		}
+
+		return OutcomeFailure
+	}
+}
+
+// 错误类别，用于Config.ErrorWeights
+const (
+	ErrorClassTimeout           = "timeout"            // 调用超时
+	ErrorClass5xx               = "5xx"                // 服务端返回5xx
+	ErrorClassConnectionRefused = "connection_refused" // 连接被拒绝
+)
+
+// 默认的错误类别判断，无法识别的错误返回空字符串
+func DefaultErrorClass(err error) string {
+	var se *HTTPStatusError
+	var ne net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassConnectionRefused
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCallHung):
+		return ErrorClassTimeout
+	case errors.As(err, &ne) && ne.Timeout():
+		return ErrorClassTimeout
+	case errors.As(err, &se) && se.Code >= 500:
+		return ErrorClass5xx
+	}
+
+	return ""
+}
+
+// 失败调用计入失败阈值的权重，未配置权重的错误类别为1，调用方需持有锁
+func (breaker *Breaker) errorWeight(config *Config, err error) float64 {
+	if len(config.ErrorWeights) == 0 || err == nil {
+		return 1
+	}
+	classOf := breaker.ErrorClassOf
+	if classOf == nil {
+		classOf = DefaultErrorClass
+	}
+	if w, ok := config.ErrorWeights[classOf(err)]; ok {
+		return w
+	}
+
+	return 1
+}
+
+// 设置rpc资源r的调用结果分类器，c为空时恢复使用默认分类器
+func (breaker *Breaker) SetClassifier(r string, c Classifier) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	if c == nil {
+		delete(breaker.classifiers, r)
+		return
+	}
+	breaker.classifiers[r] = c
+}
+
+// 对rpc资源r的调用结果分类
+func (breaker *Breaker) classify(r string, err error) Outcome {
+	breaker.RLock()
+	c, ok := breaker.classifiers[r]
+	if !ok {
+		c = breaker.Classifier
+	}
+	breaker.RUnlock()
+
+	if c == nil {
+		return DefaultClassifier(err)
+	}
+
+	return c(err)
+}
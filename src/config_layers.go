@@ -0,0 +1,174 @@
+package governance
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// 配置层级，按优先级从低到高排列
+const (
+	LayerDefault  = "default"  // 熔断器的默认配置
+	LayerService  = "service"  // 服务级配置，以服务名设置
+	LayerMethod   = "method"   // 方法级配置，以ResourceKey{Service, Method}设置
+	LayerInstance = "instance" // 实例级配置，以ResourceKey{Service, Instance}设置
+	LayerResource = "resource" // 以完整的rpc资源名设置的配置
+	LayerTuned    = "tuned"    // 由SLOController等自动调整的字段，以完整的rpc资源名设置，见SetTunedConfig
+)
+
+// 配置层
+type configLayer struct {
+	name   string
+	key    string
+	config *Config
+}
+
+// rpc资源生效的配置及各字段的来源
+type EffectiveConfig struct {
+	Resource string            `json:"resource"`
+	Config   Config            `json:"config"`
+	Sources  map[string]string `json:"sources"` // 字段的toml名对应的配置层级
+}
+
+// rpc资源r从低到高的配置层，只包含设置了配置的层，def为默认配置，configs为各层级设置的配置，tuned为自动调整的配置
+func configLayers(def *Config, configs, tuned map[string]*Config, r string) []configLayer {
+	layers := []configLayer{{name: LayerDefault, config: def}}
+	add := func(name, key string) {
+		for _, l := range layers {
+			if l.key == key {
+				return
+			}
+		}
+		if c, ok := configs[key]; ok && c != nil {
+			layers = append(layers, configLayer{name: name, key: key, config: c})
+		}
+	}
+
+	k := DecodeResourceKey(r)
+	add(LayerService, k.Service)
+	add(LayerService, ResourceKey{Service: k.Service}.Encode())
+	if k.Method != "" {
+		add(LayerMethod, ResourceKey{Service: k.Service, Method: k.Method}.Encode())
+	}
+	if k.Instance != "" {
+		add(LayerInstance, ResourceKey{Service: k.Service, Instance: k.Instance}.Encode())
+	}
+	add(LayerResource, r)
+	if c, ok := tuned[r]; ok && c != nil {
+		layers = append(layers, configLayer{name: LayerTuned, key: r, config: c})
+	}
+
+	return layers
+}
+
+// 按层级合并rpc资源r的配置，高层级中非零值的字段覆盖低层级，返回合并后的配置和各字段的来源
+func resolveConfig(def *Config, configs, tuned map[string]*Config, r string) (*Config, map[string]string) {
+	layers := configLayers(def, configs, tuned, r)
+	if len(layers) == 1 {
+		return def, nil
+	}
+
+	config := *def
+	sources := make(map[string]string)
+	dst := reflect.ValueOf(&config).Elem()
+	t := dst.Type()
+	for _, l := range layers[1:] {
+		src := reflect.ValueOf(l.config).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			if src.Field(i).IsZero() {
+				continue
+			}
+			dst.Field(i).Set(src.Field(i))
+			sources[configFieldName(t.Field(i))] = l.name
+		}
+	}
+
+	return &config, sources
+}
+
+// 字段的toml名
+func configFieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("toml"); tag != "" {
+		return strings.Split(tag, ",")[0]
+	}
+
+	return f.Name
+}
+
+// 获取rpc资源r生效的配置以及每个字段来自哪一层
+// 配置按默认 < 服务 < 方法 < 实例 < 资源 < 自动调整的优先级合并，零值字段继承低层级的值
+func (breaker *Breaker) ResolveEffectiveConfig(r string) EffectiveConfig {
+	breaker.RLock()
+	config, sources := resolveConfig(breaker.Config, breaker.Configs, breaker.tuned, r)
+	breaker.RUnlock()
+
+	effective := EffectiveConfig{
+		Resource: r,
+		Config:   *config,
+		Sources:  make(map[string]string),
+	}
+	t := reflect.TypeOf(effective.Config)
+	for i := 0; i < t.NumField(); i++ {
+		name := configFieldName(t.Field(i))
+		if source, ok := sources[name]; ok {
+			effective.Sources[name] = source
+		} else {
+			effective.Sources[name] = LayerDefault
+		}
+	}
+
+	return effective
+}
+
+// 获取rpc资源r合并后的配置，所属服务处于发布模式时按发布模式放大，调用方需持有锁
+func (breaker *Breaker) configOf(r string) *Config {
+	deploy := len(breaker.deploys) > 0 && breaker.inDeployMode(DecodeResourceKey(r).Service)
+	if len(breaker.Configs) == 0 && len(breaker.tuned) == 0 && !deploy {
+		return breaker.Config
+	}
+
+	key := r
+	if deploy {
+		key += deployConfigSuffix
+	}
+	cache := breaker.effective
+	if c, ok := cache.Load(key); ok {
+		return c.(*Config)
+	}
+	config, _ := resolveConfig(breaker.Config, breaker.Configs, breaker.tuned, r)
+	if deploy {
+		config = breaker.deployConfig().relax(config)
+	}
+	cache.Store(key, config)
+
+	return config
+}
+
+// 设置rpc资源r自动调整的配置，只需设置调整的字段，其余字段继承手工设置的配置，config为空时清除自动调整
+func (breaker *Breaker) SetTunedConfig(r string, config *Config) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	defer breaker.invalidateConfigs()
+	if config == nil {
+		delete(breaker.tuned, r)
+		return
+	}
+	if breaker.tuned == nil {
+		breaker.tuned = make(map[string]*Config)
+	}
+	breaker.tuned[r] = config
+}
+
+// 获取rpc资源r自动调整的配置，未调整时返回空
+func (breaker *Breaker) TunedConfig(r string) *Config {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	return breaker.tuned[r]
+}
+
+// 配置变更后清空合并后配置的缓存，调用方需持有写锁
+func (breaker *Breaker) invalidateConfigs() {
+	breaker.effective = &sync.Map{}
+}
@@ -0,0 +1,124 @@
+package governance
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"time"
+)
+
+// 证书文件中没有可用的CA证书
+var ErrInvalidCA = errors.New("governance: no valid CA certificate")
+
+// rpc资源的实时状态
+type ResourceState struct {
+	Resource string            `json:"resource"`
+	Status   BreakerStatus     `json:"status"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Stat
+}
+
+// 控制面操作，由proto/control.proto生成的gRPC服务端调用
+type ControlPlane struct {
+	Breaker     *Breaker
+	Rules       *RuleManager
+	Coordinator *Coordinator // 人工操作协调器，设置后强制操作同步到服务的所有实例，为空时只作用于本实例
+}
+
+// 创建控制面
+func NewControlPlane(breaker *Breaker, rules *RuleManager) *ControlPlane {
+	return &ControlPlane{
+		Breaker: breaker,
+		Rules:   rules,
+	}
+}
+
+// 推送版本为version的规则集，rollback为true时回滚到version，返回当前生效的版本
+func (c *ControlPlane) PushRules(version int64, rules *RuleSet, rollback bool) (int64, error) {
+	var err error
+	if rollback {
+		err = c.Rules.Rollback(version)
+	} else {
+		err = c.Rules.ApplyRules(version, rules)
+	}
+
+	return c.Rules.CurrentVersion(), err
+}
+
+// 待人工确认的规则变更计划
+func (c *ControlPlane) PendingRules() []*RulePlan {
+	return c.Rules.Pending()
+}
+
+// 确认或丢弃待确认的规则版本version，返回当前生效的版本
+func (c *ControlPlane) ConfirmRules(version int64, confirm bool) (int64, error) {
+	var err error
+	if confirm {
+		err = c.Rules.Confirm(version)
+	} else {
+		err = c.Rules.Discard(version)
+	}
+
+	return c.Rules.CurrentVersion(), err
+}
+
+// 查询所有rpc资源的实时状态，按资源名排序
+func (c *ControlPlane) QueryStats() []ResourceState {
+	return c.Breaker.Snapshot()
+}
+
+// 查询标签匹配filter的rpc资源的实时状态
+func (c *ControlPlane) QueryStatsByTags(filter TagFilter) []ResourceState {
+	return c.Breaker.SnapshotByTags(filter)
+}
+
+// 强制设置rpc资源r的熔断状态，强制打开在ttl后自动恢复，operator为操作人
+// 设置了Coordinator时同步到所有实例，此时ttl不大于0的强制打开使用DefaultForceOpenTTL；否则只作用于本实例，ttl不大于0时不自动恢复
+func (c *ControlPlane) ForceState(ctx context.Context, r string, status BreakerStatus, ttl time.Duration, operator string) error {
+	if c.Coordinator != nil {
+		return c.Coordinator.Force(ctx, r, status, ttl, operator)
+	}
+
+	if status == OpenStatus && ttl > 0 {
+		c.Breaker.ForceOpenFor(r, ttl)
+		return nil
+	}
+	c.Breaker.ForceState(r, status)
+
+	return nil
+}
+
+// 服务service进入发布模式直到until，until不晚于当前时间时退出发布模式
+func (c *ControlPlane) DeployMode(service string, until time.Time) {
+	if !until.After(time.Now()) {
+		c.Breaker.ExitDeployMode(service)
+		return
+	}
+	c.Breaker.EnterDeployMode(service, until)
+}
+
+// 创建控制面服务端的mTLS配置，要求并校验客户端证书
+func ControlPlaneTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, ErrInvalidCA
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
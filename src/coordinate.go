@@ -0,0 +1,178 @@
+package governance
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 需要同步到服务所有实例的人工操作
+type ForcedOperation struct {
+	Resource string    `json:"resource"` // rpc资源名
+	Status   string    `json:"status"`   // 强制设置的状态，close、half-open或open
+	Expire   time.Time `json:"expire"`   // 强制打开的到期时间
+	Operator string    `json:"operator"` // 操作人
+}
+
+// 人工操作的广播通道，可基于注册中心元数据或消息队列的发布订阅实现
+type OperationBus interface {
+	Publish(ctx context.Context, op ForcedOperation) error
+	// 订阅人工操作，ctx结束前持续调用handle
+	Subscribe(ctx context.Context, handle func(op ForcedOperation)) error
+}
+
+// 人工操作协调器，将一个实例上的强制操作同步到服务的所有实例
+type Coordinator struct {
+	Breaker *Breaker
+	Bus     OperationBus
+}
+
+// 创建人工操作协调器
+func NewCoordinator(breaker *Breaker, bus OperationBus) *Coordinator {
+	return &Coordinator{
+		Breaker: breaker,
+		Bus:     bus,
+	}
+}
+
+// 强制打开操作未指定持续时间时的默认值，同步到所有实例的强制打开必须到期
+const DefaultForceOpenTTL = 10 * time.Minute
+
+// 在所有实例上强制打开rpc资源r的熔断，ttl后自动恢复
+func (c *Coordinator) ForceOpen(ctx context.Context, r string, ttl time.Duration, operator string) error {
+	return c.Force(ctx, r, OpenStatus, ttl, operator)
+}
+
+// 在所有实例上强制关闭rpc资源r的熔断
+func (c *Coordinator) ForceClose(ctx context.Context, r string, operator string) error {
+	return c.Force(ctx, r, CloseStatus, 0, operator)
+}
+
+// 在所有实例上强制设置rpc资源r的熔断状态，强制打开在ttl后自动恢复，ttl不大于0时使用DefaultForceOpenTTL
+// 发布成功后立即应用到本实例，不依赖本实例是否运行Run
+func (c *Coordinator) Force(ctx context.Context, r string, status BreakerStatus, ttl time.Duration, operator string) error {
+	op := ForcedOperation{
+		Resource: r,
+		Status:   statusName(status),
+		Operator: operator,
+	}
+	if status == OpenStatus {
+		if ttl <= 0 {
+			ttl = DefaultForceOpenTTL
+		}
+		op.Expire = time.Now().Add(ttl)
+	}
+	if err := c.Bus.Publish(ctx, op); err != nil {
+		return err
+	}
+	c.apply(op)
+
+	return nil
+}
+
+// 接收其他实例发布的操作并应用到本实例，ctx结束时返回
+func (c *Coordinator) Run(ctx context.Context) error {
+	return c.Bus.Subscribe(ctx, c.apply)
+}
+
+// 应用人工操作，已过期的强制打开操作被忽略
+func (c *Coordinator) apply(op ForcedOperation) {
+	status, ok := statusByName[op.Status]
+	if !ok {
+		return
+	}
+
+	if status == OpenStatus {
+		ttl := time.Until(op.Expire)
+		if ttl <= 0 {
+			return
+		}
+		c.Breaker.ForceOpenFor(op.Resource, ttl)
+		return
+	}
+	c.Breaker.ForceState(op.Resource, status)
+}
+
+// 基于共享存储的人工操作广播通道，操作按proto/state.proto中的ForcedOperation编码后以递增序号写入key/序号，
+// key保存最新的序号用于通知，订阅方按序号读取所有未处理的操作，同时发布的操作或被合并的通知不会丢失
+type storeOperationBus struct {
+	store Store
+	key   string
+}
+
+// 每条操作在共享存储中的保留时间
+const operationLogRetention = 10 * time.Minute
+
+// 创建基于共享存储的人工操作广播通道
+func NewStoreOperationBus(store Store, key string) OperationBus {
+	return &storeOperationBus{store: store, key: key}
+}
+
+// 分配序号的key
+func (b *storeOperationBus) seqKey() string {
+	return b.key + "/seq"
+}
+
+// 序号为seq的操作的key
+func (b *storeOperationBus) entryKey(seq int64) string {
+	return b.key + "/" + strconv.FormatInt(seq, 10)
+}
+
+func (b *storeOperationBus) Publish(ctx context.Context, op ForcedOperation) error {
+	seq, err := b.store.Incr(ctx, b.seqKey(), 1, 0)
+	if err != nil {
+		return err
+	}
+	if err := b.store.Set(ctx, b.entryKey(seq), op.Marshal(), operationLogRetention); err != nil {
+		return err
+	}
+
+	return b.store.Set(ctx, b.key, []byte(strconv.FormatInt(seq, 10)), 0)
+}
+
+// 订阅之后发布的操作，每次通知时按序号处理到目前最大的序号
+func (b *storeOperationBus) Subscribe(ctx context.Context, handle func(op ForcedOperation)) error {
+	var next int64 = 1
+	value, ok, err := b.store.Get(ctx, b.seqKey())
+	if err != nil {
+		return err
+	}
+	if ok {
+		n, _ := strconv.ParseInt(string(value), 10, 64)
+		next = n + 1
+	}
+
+	var mu sync.Mutex
+	latest, missing := next-1, int64(0)
+	return b.store.Watch(ctx, b.key, func(value []byte, ok bool) {
+		seq, err := strconv.ParseInt(string(value), 10, 64)
+		if !ok || err != nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if seq > latest {
+			latest = seq
+		}
+		for ; next <= latest; next++ {
+			data, ok, err := b.store.Get(ctx, b.entryKey(next))
+			if err != nil {
+				return
+			}
+			if !ok {
+				// 序号已分配但操作还未写入，等待下次通知，下次通知时仍未写入视为发布失败并跳过
+				if missing != next {
+					missing = next
+					return
+				}
+				continue
+			}
+			var op ForcedOperation
+			if op.Unmarshal(data) == nil {
+				handle(op)
+			}
+		}
+	})
+}
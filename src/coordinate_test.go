@@ -0,0 +1,47 @@
+package governance
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 控制面配置了协调器时，强制打开同步到其他实例并到期恢复
+func TestControlPlaneForcePropagates(t *testing.T) {
+	store := NewMemoryStore()
+	newInstance := func() (*Breaker, *ControlPlane) {
+		config := DefaultConfig()
+		breaker := newBreaker(&config)
+		control := NewControlPlane(breaker, NewRuleManager(breaker))
+		control.Coordinator = NewCoordinator(breaker, NewStoreOperationBus(store, "ops"))
+		return breaker, control
+	}
+	a, control := newInstance()
+	b, peer := newInstance()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go peer.Coordinator.Run(ctx)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := control.ForceState(ctx, "svc", OpenStatus, 100*time.Millisecond, "alice"); err != nil {
+		t.Fatalf("ForceState: %v", err)
+	}
+	if a.State("svc") != OpenStatus {
+		t.Fatal("local instance not forced open")
+	}
+	deadline := time.Now().Add(time.Second)
+	for b.State("svc") != OpenStatus {
+		if time.Now().After(deadline) {
+			t.Fatal("peer instance not forced open")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	later := time.Now().Add(time.Second)
+	a.tick(later)
+	b.tick(later)
+	if a.State("svc") != HalfOpenStatus || b.State("svc") != HalfOpenStatus {
+		t.Fatal("forced open did not expire")
+	}
+}
@@ -0,0 +1,81 @@
+package governance
+
+import (
+	"context"
+)
+
+// 数据库读写分离的熔断和限流配置
+type DBConfig struct {
+	Read         Config         `toml:"read"`          // 读操作熔断配置
+	Write        Config         `toml:"write"`         // 写操作熔断配置
+	ReadLimiter  *LimiterConfig `toml:"read_limiter"`  // 读操作限流配置，按目标库限流，为空时不限流
+	WriteLimiter *LimiterConfig `toml:"write_limiter"` // 写操作限流配置，为空时不限流
+}
+
+// 数据库熔断器，读写操作使用独立的熔断和限流配置，主库熔断打开时读操作转到从库
+type DBBreaker struct {
+	Primary      string   // 主库rpc资源名
+	Replicas     []string // 从库rpc资源名
+	Read         *Breaker // 读操作熔断器
+	Write        *Breaker // 写操作熔断器
+	ReadLimiter  *Limiter // 读操作限流器，以目标库为调用方，为空时不限流
+	WriteLimiter *Limiter // 写操作限流器，为空时不限流
+}
+
+// 初始化数据库熔断器
+func InitDBBreaker(config *DBConfig, primary string, replicas ...string) *DBBreaker {
+	db := &DBBreaker{
+		Primary:  primary,
+		Replicas: replicas,
+		Read:     InitBreaker(&config.Read),
+		Write:    InitBreaker(&config.Write),
+	}
+	if config.ReadLimiter != nil {
+		db.ReadLimiter = NewLimiter(config.ReadLimiter)
+	}
+	if config.WriteLimiter != nil {
+		db.WriteLimiter = NewLimiter(config.WriteLimiter)
+	}
+
+	return db
+}
+
+// 执行读操作，fn的target参数为本次读取的库，被限流时返回ErrRateLimited
+func (db *DBBreaker) ExecRead(ctx context.Context, fn func(ctx context.Context, target string) error) error {
+	target := db.readTarget()
+	if db.ReadLimiter != nil {
+		if err := db.ReadLimiter.Wait(ctx, target); err != nil {
+			return err
+		}
+	}
+
+	return db.Read.Execute(ctx, target, func(ctx context.Context) error {
+		return fn(ctx, target)
+	})
+}
+
+// 执行写操作，写操作只能在主库执行，被限流时返回ErrRateLimited
+func (db *DBBreaker) ExecWrite(ctx context.Context, fn func(ctx context.Context) error) error {
+	if db.WriteLimiter != nil {
+		if err := db.WriteLimiter.Wait(ctx, db.Primary); err != nil {
+			return err
+		}
+	}
+
+	return db.Write.Execute(ctx, db.Primary, fn)
+}
+
+// 选择读操作的目标库，主库读或写熔断打开时选择第一个未熔断的从库，只查询熔断状态，不计入拒绝统计
+func (db *DBBreaker) readTarget() string {
+	if db.Read.Available(db.Primary) && db.Write.Available(db.Primary) {
+		return db.Primary
+	}
+
+	for _, replica := range db.Replicas {
+		if db.Read.Available(replica) {
+			return replica
+		}
+	}
+
+	return db.Primary
+}
@@ -0,0 +1,34 @@
+package governance
+
+import (
+	"context"
+	"testing"
+)
+
+// 读写操作使用各自的限流器，读操作的限流不影响写操作
+func TestDBSeparateLimiters(t *testing.T) {
+	db := InitDBBreaker(&DBConfig{
+		Read:         DefaultConfig(),
+		Write:        DefaultConfig(),
+		ReadLimiter:  &LimiterConfig{QPS: 1, Burst: 1},
+		WriteLimiter: &LimiterConfig{QPS: 1, Burst: 2},
+	}, "primary", "replica")
+	ctx := context.Background()
+	read := func(ctx context.Context, target string) error { return nil }
+	write := func(ctx context.Context) error { return nil }
+
+	if err := db.ExecRead(ctx, read); err != nil {
+		t.Fatalf("first read: %v", err)
+	}
+	if err := db.ExecRead(ctx, read); err != ErrRateLimited {
+		t.Fatalf("second read = %v, want %v", err, ErrRateLimited)
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.ExecWrite(ctx, write); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	if err := db.ExecWrite(ctx, write); err != ErrRateLimited {
+		t.Fatalf("third write = %v, want %v", err, ErrRateLimited)
+	}
+}
@@ -0,0 +1,83 @@
+package governance
+
+import (
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// 调试接口路径
+const (
+	DebugPath             = "/debug/governance"
+	DebugStateMachinePath = "/debug/governance/statemachine"
+	DebugConfigPath       = "/debug/governance/config"
+)
+
+// 熔断器内部状态
+type DebugInfo struct {
+	Resources   int   `json:"resources"`    // rpc资源数
+	Configs     int   `json:"configs"`      // 单独设置配置的rpc资源数
+	Disabled    int   `json:"disabled"`     // 运行时关闭熔断的rpc资源数
+	AllDisabled bool  `json:"all_disabled"` // 是否运行时关闭了所有rpc资源的熔断
+	Stats       int   `json:"stats"`        // 有调用统计的rpc资源数
+	EventQueue  int   `json:"event_queue"`  // 待分发的状态变更通知数，达到容量后新的通知被丢弃
+	EventCap    int   `json:"event_cap"`    // 状态变更通知队列的容量，未订阅时为0
+	LockWait    int64 `json:"lock_wait_ns"` // 获取熔断器锁的耗时，单位纳秒
+	LastTick    int64 `json:"last_tick"`    // 后台定时任务最近一次执行的时间，单位纳秒
+	TickHealthy bool  `json:"tick_healthy"` // 后台定时任务是否正常执行
+	Goroutines  int   `json:"goroutines"`   // 进程的goroutine数
+}
+
+// 获取熔断器内部状态
+func (breaker *Breaker) DebugInfo() DebugInfo {
+	start := time.Now()
+	breaker.Lock()
+	lockWait := time.Since(start)
+	info := DebugInfo{
+		Resources:  len(breaker.R),
+		Configs:    len(breaker.Configs),
+		Disabled:   len(breaker.disabled),
+		Stats:      len(breaker.stats),
+		EventQueue: len(breaker.events),
+		EventCap:   cap(breaker.events),
+	}
+	// 只读取状态，释放时不使状态快照失效
+	breaker.unlockCounters()
+
+	info.LockWait = lockWait.Nanoseconds()
+	info.AllDisabled = breaker.AllDisabled()
+	info.LastTick = atomic.LoadInt64(&breaker.lastTick)
+	// 后台定时任务超过两个周期未执行视为异常
+	maxIdle := 2 * time.Duration(float64(breaker.Config.tickInterval())*(1+breaker.Config.TickJitter))
+	// 按单调时间判断，系统时间跳变不会误判
+	info.TickHealthy = breaker.lazy || info.LastTick == 0 || time.Duration(monoNow()-atomic.LoadInt64(&breaker.lastTickMono)) < maxIdle
+	info.Goroutines = runtime.NumGoroutine()
+
+	return info
+}
+
+// 在mux上注册调试接口，输出熔断器内部状态
+func RegisterDebugHandlers(mux *http.ServeMux, breaker *Breaker) {
+	mux.HandleFunc(DebugPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, breaker.DebugInfo())
+	})
+	// 参数resource指定rpc资源，输出生效的配置及各字段来自哪一层
+	mux.HandleFunc(DebugConfigPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, breaker.ResolveEffectiveConfig(r.FormValue("resource")))
+	})
+	// 参数resource指定rpc资源，format为dot或mermaid时输出对应格式的图，否则输出JSON
+	mux.HandleFunc(DebugStateMachinePath, func(w http.ResponseWriter, r *http.Request) {
+		m := breaker.StateMachine(r.FormValue("resource"))
+		switch r.FormValue("format") {
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+			w.Write([]byte(m.DOT()))
+		case "mermaid":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte(m.Mermaid()))
+		default:
+			writeJSON(w, m)
+		}
+	})
+}
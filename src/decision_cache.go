@@ -0,0 +1,59 @@
+package governance
+
+import (
+	"context"
+	"sync"
+)
+
+// 熔断判断缓存中的键
+type decisionKey struct {
+	breaker *Breaker
+	r       string
+}
+
+// 请求级别的熔断判断缓存，同一请求内对同一rpc资源只判断一次，减少热循环中的锁和原子操作
+// 半开状态下的判断不缓存，每次调用都需经过探测选择；调用失败时清除缓存，下次调用重新判断
+type decisionCache struct {
+	sync.Mutex
+	decisions map[decisionKey]bool
+}
+
+type decisionCacheKey struct{}
+
+// 为请求开启熔断判断缓存，适用于单个请求内多次调用同一rpc资源的处理函数
+func WithDecisionCache(ctx context.Context) context.Context {
+	if _, ok := decisionCacheFrom(ctx); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, decisionCacheKey{}, &decisionCache{decisions: make(map[decisionKey]bool)})
+}
+
+// 获取请求的熔断判断缓存
+func decisionCacheFrom(ctx context.Context) (*decisionCache, bool) {
+	c, ok := ctx.Value(decisionCacheKey{}).(*decisionCache)
+	return c, ok
+}
+
+func (c *decisionCache) get(breaker *Breaker, r string) (allowed, ok bool) {
+	c.Lock()
+	allowed, ok = c.decisions[decisionKey{breaker, r}]
+	c.Unlock()
+
+	return allowed, ok
+}
+
+func (c *decisionCache) set(breaker *Breaker, r string, allowed bool) {
+	c.Lock()
+	c.decisions[decisionKey{breaker, r}] = allowed
+	c.Unlock()
+}
+
+// 清除请求ctx中rpc资源r的熔断判断缓存
+func (breaker *Breaker) forgetDecision(ctx context.Context, r string) {
+	if c, ok := decisionCacheFrom(ctx); ok {
+		c.Lock()
+		delete(c.decisions, decisionKey{breaker, r})
+		c.Unlock()
+	}
+}
@@ -0,0 +1,165 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 降级方案不存在
+var ErrProfileNotFound = errors.New("governance: degradation profile not found")
+
+// 降级方案，如只读模式、精简模式，生效时原子地切换一组开关
+type DegradeProfile struct {
+	Name  string          `toml:"name"`  // 方案名
+	Flags map[string]bool `toml:"flags"` // 生效时设置的开关
+}
+
+// 外部开关系统，如LaunchDarkly、Unleash或自研开关平台
+type FlagProvider interface {
+	// 查询开关name，开关系统中不存在时第二个返回值为false
+	Lookup(ctx context.Context, name string) (bool, bool)
+}
+
+// 开关名前缀
+const (
+	DegradeFlagPrefix  = "degrade."  // 外部开关系统中控制降级方案的开关，如degrade.read-only
+	FallbackFlagPrefix = "fallback." // 强制rpc资源走降级逻辑的开关，如fallback.user-service
+)
+
+// 降级管理器
+type Degrader struct {
+	Provider FlagProvider // 外部开关系统，为空时只使用降级方案中的开关
+
+	sync.RWMutex
+	profiles map[string]*DegradeProfile
+	active   map[string]bool // 生效中的方案
+	flags    map[string]bool // 生效方案合并后的开关
+}
+
+// 创建降级管理器
+func NewDegrader(profiles []*DegradeProfile) *Degrader {
+	d := &Degrader{
+		profiles: make(map[string]*DegradeProfile, len(profiles)),
+		active:   make(map[string]bool),
+		flags:    make(map[string]bool),
+	}
+	for _, p := range profiles {
+		d.profiles[p.Name] = p
+	}
+
+	return d
+}
+
+// 启用降级方案name
+func (d *Degrader) Activate(name string) error {
+	return d.set(name, true)
+}
+
+// 停用降级方案name
+func (d *Degrader) Deactivate(name string) error {
+	return d.set(name, false)
+}
+
+// 判断降级方案name是否生效
+func (d *Degrader) IsActive(name string) bool {
+	d.RLock()
+	defer d.RUnlock()
+
+	return d.active[name]
+}
+
+// 获取开关flag的值，优先使用外部开关系统中的值，其次使用生效方案中的值，均未设置时返回false
+func (d *Degrader) Flag(ctx context.Context, flag string) bool {
+	if d.Provider != nil {
+		if v, ok := d.Provider.Lookup(ctx, flag); ok {
+			return v
+		}
+	}
+
+	d.RLock()
+	defer d.RUnlock()
+
+	return d.flags[flag]
+}
+
+// 按外部开关系统中degrade.方案名的开关启用和停用降级方案
+func (d *Degrader) SyncProfiles(ctx context.Context) {
+	if d.Provider == nil {
+		return
+	}
+
+	d.RLock()
+	names := make([]string, 0, len(d.profiles))
+	for name := range d.profiles {
+		names = append(names, name)
+	}
+	d.RUnlock()
+
+	for _, name := range names {
+		if v, ok := d.Provider.Lookup(ctx, DegradeFlagPrefix+name); ok {
+			d.set(name, v)
+		}
+	}
+}
+
+// 调用rpc资源r，开关fallback.r打开时直接走降级逻辑，调用失败时也走降级逻辑，fallback的err为调用失败的错误
+func (d *Degrader) Execute(ctx context.Context, breaker *Breaker, r string,
+	fn func(ctx context.Context) error, fallback func(ctx context.Context, err error) error) error {
+	if d.Flag(ctx, FallbackFlagPrefix+r) {
+		return fallback(ctx, nil)
+	}
+
+	if err := breaker.Execute(ctx, r, fn); err != nil {
+		return fallback(ctx, err)
+	}
+
+	return nil
+}
+
+func (d *Degrader) set(name string, active bool) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if _, ok := d.profiles[name]; !ok {
+		return ErrProfileNotFound
+	}
+	if active {
+		d.active[name] = true
+	} else {
+		delete(d.active, name)
+	}
+
+	// 按生效的方案重新合并开关，多个方案设置同一开关时任一为true即为true
+	flags := make(map[string]bool)
+	for n := range d.active {
+		for flag, v := range d.profiles[n].Flags {
+			flags[flag] = flags[flag] || v
+		}
+	}
+	d.flags = flags
+
+	return nil
+}
+
+// 降级触发规则，rpc资源熔断打开时启用方案，关闭时停用
+type DegradeTrigger struct {
+	Resource string `toml:"resource"` // 触发降级的rpc资源
+	Profile  string `toml:"profile"`  // 启用的降级方案
+}
+
+// 监听熔断状态变更，按触发规则自动启用和停用降级方案
+func (d *Degrader) Watch(breaker *Breaker, triggers []DegradeTrigger) {
+	breaker.Subscribe(func(change StateChange) {
+		for _, t := range triggers {
+			if t.Resource != change.Resource {
+				continue
+			}
+			if change.To == OpenStatus {
+				d.Activate(t.Profile)
+			} else if change.To == CloseStatus {
+				d.Deactivate(t.Profile)
+			}
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package governance
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// 发布模式配置，发布期间放宽熔断并减少重试，避免滚动重启造成误熔断和重试放大
+type DeployModeConfig struct {
+	OpenTimeoutScale   float64 `toml:"open_timeout_scale"`   // 熔断打开时间的放大倍数，默认2
+	FailThresholdScale float64 `toml:"fail_threshold_scale"` // 失败阈值的放大倍数，默认2
+	MaxAttempts        int     `toml:"max_attempts"`         // 发布期间的最大尝试次数，默认1即不重试
+}
+
+// 默认的发布模式配置
+func defaultDeployModeConfig() *DeployModeConfig {
+	return &DeployModeConfig{
+		OpenTimeoutScale:   2,
+		FailThresholdScale: 2,
+		MaxAttempts:        1,
+	}
+}
+
+// 发布模式下合并后配置缓存的key后缀
+const deployConfigSuffix = "\x00deploy"
+
+// 服务service进入发布模式直到until，期间该服务所有rpc资源的失败阈值和熔断打开时间按DeployConfig放大
+// 由发布工具在滚动发布开始时调用，重复调用以最后一次的until为准
+func (breaker *Breaker) EnterDeployMode(service string, until time.Time) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	now := breaker.now()
+	for s, t := range breaker.deploys {
+		if !now.Before(t) {
+			delete(breaker.deploys, s)
+		}
+	}
+	if breaker.deploys == nil {
+		breaker.deploys = make(map[string]time.Time)
+	}
+	breaker.deploys[service] = until
+	logDecision(context.Background(), LogRules, slog.LevelInfo, "deploy mode entered", service, "deploy", "relax",
+		slog.Time("until", until))
+}
+
+// 服务service提前退出发布模式
+func (breaker *Breaker) ExitDeployMode(service string) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	if _, ok := breaker.deploys[service]; ok {
+		delete(breaker.deploys, service)
+		logDecision(context.Background(), LogRules, slog.LevelInfo, "deploy mode exited", service, "deploy", "restore")
+	}
+}
+
+// 判断服务service是否处于发布模式
+func (breaker *Breaker) InDeployMode(service string) bool {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	return breaker.inDeployMode(service)
+}
+
+// 判断服务service是否处于发布模式，调用方需持有锁
+func (breaker *Breaker) inDeployMode(service string) bool {
+	until, ok := breaker.deploys[service]
+	return ok && breaker.now().Before(until)
+}
+
+// 发布模式配置，未设置时使用默认值
+func (breaker *Breaker) deployConfig() *DeployModeConfig {
+	if breaker.DeployConfig == nil {
+		return defaultDeployModeConfig()
+	}
+
+	return breaker.DeployConfig
+}
+
+// 按发布模式放大配置
+func (c *DeployModeConfig) relax(config *Config) *Config {
+	relaxed := *config
+	if c.OpenTimeoutScale > 1 {
+		timeout, max := relaxed.openTimeouts()
+		relaxed.OpenTimeout = time.Duration(float64(timeout) * c.OpenTimeoutScale)
+		relaxed.MaxOpenTimeout = time.Duration(float64(max) * c.OpenTimeoutScale)
+	}
+	if c.FailThresholdScale > 1 {
+		relaxed.FailThreshold = int(float64(relaxed.FailThreshold) * c.FailThresholdScale)
+	}
+
+	return &relaxed
+}
+
+// 服务service进入发布模式直到until，见Breaker.EnterDeployMode，同时将该服务的重试次数降为DeployConfig.MaxAttempts
+func (g *Governance) EnterDeployMode(service string, until time.Time) {
+	g.Breaker.EnterDeployMode(service, until)
+}
+
+// 服务service提前退出发布模式
+func (g *Governance) ExitDeployMode(service string) {
+	g.Breaker.ExitDeployMode(service)
+}
+
+// rpc资源r使用的重试器，方法级配置了重试次数时优先使用，所属服务处于发布模式时按发布模式限制尝试次数，未配置重试时返回空
+func (g *Governance) retryFor(r string) *Retry {
+	retry := g.Retry
+	if m := g.methodOf(r); m != nil && m.retry != nil {
+		retry = m.retry
+	}
+	if retry == nil || !g.Breaker.InDeployMode(DecodeResourceKey(r).Service) {
+		return retry
+	}
+
+	config := *retry.Config
+	if max := g.Breaker.deployConfig().MaxAttempts; max > 0 && max < config.MaxAttempts {
+		config.MaxAttempts = max
+	}
+
+	return &Retry{Config: &config, Classifier: retry.Classifier}
+}
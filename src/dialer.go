@@ -0,0 +1,71 @@
+package governance
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// 建立连接的拨号器，net.Dialer和TrackingDialer均实现了此接口
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// 连接层治理的拨号器，按目标地址熔断连续的建连失败并限制建连速率，适用于非HTTP的TCP协议
+type GovernedDialer struct {
+	Dialer         ContextDialer // 实际建立连接的拨号器，为空时使用零值net.Dialer
+	Breaker        *Breaker      // 按目标地址熔断，rpc资源为ResourceKey{Service, Instance: 地址}
+	Limiter        *Limiter      // 按目标地址限制建连速率，为空时不限制
+	Service        string        // 目标服务名，用于生成rpc资源名
+	ConnectTimeout time.Duration // 建连超时时间，0表示不限制
+}
+
+// 创建连接层治理的拨号器
+func NewGovernedDialer(breaker *Breaker, service string, connectTimeout time.Duration) *GovernedDialer {
+	return &GovernedDialer{
+		Breaker:        breaker,
+		Service:        service,
+		ConnectTimeout: connectTimeout,
+	}
+}
+
+func (d *GovernedDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.Limiter != nil {
+		if err := d.Limiter.Wait(ctx, addr); err != nil {
+			return nil, err
+		}
+	}
+
+	var dialer ContextDialer = &net.Dialer{}
+	if d.Dialer != nil {
+		dialer = d.Dialer
+	}
+
+	var conn net.Conn
+	err := d.Breaker.Execute(ctx, ResourceKey{Service: d.Service, Instance: addr}.Encode(), func(ctx context.Context) error {
+		if d.ConnectTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d.ConnectTimeout)
+			defer cancel()
+		}
+		var err error
+		conn, err = dialer.DialContext(ctx, network, addr)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// 建立连接，不受ctx控制
+func (d *GovernedDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+var (
+	_ ContextDialer = (*net.Dialer)(nil)
+	_ ContextDialer = (*TrackingDialer)(nil)
+	_ ContextDialer = (*GovernedDialer)(nil)
+)
@@ -0,0 +1,124 @@
+package governance
+
+import (
+	"context"
+	"time"
+)
+
+// 目标服务的方法列表，基于gRPC server reflection的实现见GRPCReflectionLister（需grpc构建标签）
+type MethodLister interface {
+	// 返回方法全名，如/pkg.Service/Method
+	ListMethods(ctx context.Context) ([]string, error)
+}
+
+// 目标服务的方法描述，包含方法上声明的治理option，GRPCReflectionLister同时实现此接口
+type MethodDescriber interface {
+	Describe(ctx context.Context) ([]MethodDescriptor, error)
+}
+
+// 枚举目标服务的方法并预先注册为rpc资源，使用默认配置
+func (breaker *Breaker) DiscoverResources(ctx context.Context, lister MethodLister) ([]string, error) {
+	methods, err := lister.ListMethods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	for _, m := range methods {
+		breaker.ensure(m)
+	}
+
+	return methods, nil
+}
+
+// 枚举目标服务的方法并预先注册为rpc资源，方法上声明了治理option的按option生成方法级配置，其余使用默认配置
+func (g *Governance) DiscoverMethods(ctx context.Context, d MethodDescriber) ([]string, error) {
+	methods, err := d.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(methods))
+	g.Breaker.Lock()
+	for _, m := range methods {
+		g.Breaker.ensure(m.FullName())
+		names = append(names, m.FullName())
+	}
+	g.Breaker.Unlock()
+	g.ApplyMethodConfigs(LoadMethodConfigs(methods))
+
+	return names, nil
+}
+
+// 确保rpc资源r存在，调用方需持有锁
+func (breaker *Breaker) ensure(r string) *RPC {
+	v, ok := breaker.R[r]
+	if !ok {
+		v = &RPC{}
+		breaker.R[r] = v
+	}
+	if _, ok := breaker.stats[r]; !ok {
+		breaker.stats[r] = &Stat{}
+	}
+
+	return v
+}
+
+// rpc资源注册选项
+type ResourceOption func(config *Config)
+
+// 设置失败阈值
+func WithFailThreshold(n int) ResourceOption {
+	return func(config *Config) {
+		config.FailThreshold = n
+	}
+}
+
+// 设置成功阈值
+func WithSuccThreshold(n int) ResourceOption {
+	return func(config *Config) {
+		config.SuccThreshold = n
+	}
+}
+
+// 设置熔断打开时间
+func WithOpenTimeout(d time.Duration) ResourceOption {
+	return func(config *Config) {
+		config.OpenTimeout = d
+	}
+}
+
+// 设置最小请求数
+func WithMinRequestVolume(n int) ResourceOption {
+	return func(config *Config) {
+		config.MinRequestVolume = n
+	}
+}
+
+// 设置标签
+func WithTags(tags map[string]string) ResourceOption {
+	return func(config *Config) {
+		config.Tags = tags
+	}
+}
+
+// 注册rpc资源r，opts设置的字段写入资源级配置，其余字段继承低层级的配置，注册后rpc资源在首次调用前即存在
+func (breaker *Breaker) Register(r string, opts ...ResourceOption) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	if len(opts) > 0 {
+		var config Config
+		if c, ok := breaker.Configs[r]; ok && c != nil {
+			config = *c
+		}
+		for _, opt := range opts {
+			opt(&config)
+		}
+		breaker.Configs[r] = &config
+		breaker.invalidateConfigs()
+	}
+	breaker.ensure(r)
+}
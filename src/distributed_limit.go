@@ -0,0 +1,118 @@
+package governance
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 基于共享存储的固定窗口限流器，多个实例共享同一调用方的请求计数
+type DistributedLimiter struct {
+	Store  Store
+	Prefix string        // 计数key的前缀
+	Limit  int64         // 每个窗口允许的请求数
+	Window time.Duration // 窗口长度
+}
+
+// 检查窗口或周期长度，不大于0时按其计算窗口序号会除零
+func checkWindow(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("governance: invalid %s %v: must be positive", name, d)
+	}
+
+	return nil
+}
+
+// 创建分布式限流器，window不大于0时返回错误
+func NewDistributedLimiter(store Store, prefix string, limit int64, window time.Duration) (*DistributedLimiter, error) {
+	if err := checkWindow("distributed limiter window", window); err != nil {
+		return nil, err
+	}
+
+	return &DistributedLimiter{
+		Store:  store,
+		Prefix: prefix,
+		Limit:  limit,
+		Window: window,
+	}, nil
+}
+
+// 判断调用方key代价为cost的请求在当前窗口内是否允许执行，存储不可用时返回错误，由调用方决定放行或拒绝
+func (l *DistributedLimiter) AllowN(ctx context.Context, key string, cost int64) (bool, error) {
+	if err := checkWindow("distributed limiter window", l.Window); err != nil {
+		return false, err
+	}
+	window := time.Now().UnixNano() / int64(l.Window)
+	n, err := l.Store.Incr(ctx, l.Prefix+key+":"+strconv.FormatInt(window, 10), cost, 2*l.Window)
+	if err != nil {
+		return false, err
+	}
+
+	return n <= l.Limit, nil
+}
+
+// 判断调用方key的请求在当前窗口内是否允许执行，存储不可用时放行
+func (l *DistributedLimiter) Allow(key string) bool {
+	ok, err := l.AllowN(context.Background(), key, 1)
+	return ok || err != nil
+}
+
+var _ RateLimiter = (*DistributedLimiter)(nil)
+
+// 基于共享存储的配额统计，按周期累计调用方的用量
+type Quota struct {
+	Store  Store
+	Prefix string        // 用量key的前缀
+	Limit  int64         // 每个周期的配额
+	Period time.Duration // 配额周期
+}
+
+// 创建配额统计，period不大于0时返回错误
+func NewQuota(store Store, prefix string, limit int64, period time.Duration) (*Quota, error) {
+	if err := checkWindow("quota period", period); err != nil {
+		return nil, err
+	}
+
+	return &Quota{
+		Store:  store,
+		Prefix: prefix,
+		Limit:  limit,
+		Period: period,
+	}, nil
+}
+
+// 当前周期的用量key
+func (q *Quota) key(key string) string {
+	return q.Prefix + key + ":" + strconv.FormatInt(time.Now().UnixNano()/int64(q.Period), 10)
+}
+
+// 消耗调用方key的amount配额，返回剩余配额，超出配额时剩余配额为负数
+func (q *Quota) Consume(ctx context.Context, key string, amount int64) (int64, error) {
+	if err := checkWindow("quota period", q.Period); err != nil {
+		return 0, err
+	}
+	used, err := q.Store.Incr(ctx, q.key(key), amount, q.Period)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Limit - used, nil
+}
+
+// 调用方key当前周期的剩余配额
+func (q *Quota) Remaining(ctx context.Context, key string) (int64, error) {
+	if err := checkWindow("quota period", q.Period); err != nil {
+		return 0, err
+	}
+	v, ok, err := q.Store.Get(ctx, q.key(key))
+	if err != nil || !ok {
+		return q.Limit, err
+	}
+	used, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Limit - used, nil
+}
@@ -0,0 +1,309 @@
+package governance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 消耗速率告警，长短两个窗口的消耗速率都超过阈值时触发，如1小时和5分钟窗口超过14.4倍
+type BurnAlert struct {
+	Long      int64   `toml:"long"`      // 长窗口，单位秒
+	Short     int64   `toml:"short"`     // 短窗口，单位秒，用于告警条件消失后尽快恢复
+	Threshold float64 `toml:"threshold"` // 消耗速率阈值，1表示恰好在周期结束时耗尽错误预算
+	Severity  string  `toml:"severity"`  // 告警级别，如page、ticket
+}
+
+// 服务的错误预算配置
+type ErrorBudgetConfig struct {
+	Service   string      `toml:"service"`
+	Objective float64     `toml:"objective"` // 可用率目标，如0.999
+	Period    int64       `toml:"period"`    // 错误预算周期，单位秒，如30天
+	Alerts    []BurnAlert `toml:"alerts"`
+	// 剩余错误预算比例低于此值时按TightenScale收紧该服务的限流，0表示不收紧
+	TightenBelow float64 `toml:"tighten_below"`
+	TightenScale float64 `toml:"tighten_scale"` // 收紧后限流速率的缩放比例，取值0~1
+}
+
+// 消耗速率告警事件
+type BurnEvent struct {
+	Time            time.Time `json:"time"`
+	Service         string    `json:"service"`
+	Severity        string    `json:"severity"`
+	Long            int64     `json:"long"`
+	Short           int64     `json:"short"`
+	Threshold       float64   `json:"threshold"`
+	LongBurnRate    float64   `json:"long_burn_rate"`
+	ShortBurnRate   float64   `json:"short_burn_rate"`
+	BudgetRemaining float64   `json:"budget_remaining"` // 周期内剩余的错误预算比例，可为负数
+	Resolved        bool      `json:"resolved"`         // 是否为告警恢复
+}
+
+// 服务的错误预算状态
+type ErrorBudgetStatus struct {
+	Service         string             `json:"service"`
+	Objective       float64            `json:"objective"`
+	Requests        int64              `json:"requests"` // 周期内的请求数
+	Failures        int64              `json:"failures"` // 周期内的失败数
+	BudgetRemaining float64            `json:"budget_remaining"`
+	BurnRates       map[string]float64 `json:"burn_rates"` // 各告警窗口的消耗速率，key为窗口时长
+	Firing          []string           `json:"firing"`     // 正在触发的告警级别
+	Tightened       bool               `json:"tightened"`  // 是否已收紧限流
+}
+
+// 调用统计的采样
+type budgetSample struct {
+	time     time.Time
+	requests int64
+	failures int64
+}
+
+// 单个服务的错误预算跟踪状态
+type serviceBudget struct {
+	config    *ErrorBudgetConfig
+	samples   []budgetSample
+	firing    map[int]bool // 正在触发的告警，key为告警在Alerts中的下标
+	tightened bool
+}
+
+// 错误预算跟踪器，按服务的SLO定时采样调用统计，计算多窗口消耗速率并在超过阈值时告警，
+// 错误预算即将耗尽时可自动收紧限流，限流器以服务名作为调用方
+type ErrorBudgetTracker struct {
+	Breaker *Breaker
+	Limiter *Limiter              // 为空时不收紧限流
+	OnBurn  func(event BurnEvent) // 告警触发和恢复时调用
+	Webhook string                // 告警触发和恢复时以JSON POST到此地址，为空时不发送
+	Client  *http.Client
+	// 发送Webhook失败时调用，失败总会记录日志
+	OnWebhookError func(event BurnEvent, err error)
+
+	sync.Mutex
+	budgets []*serviceBudget
+}
+
+// 创建错误预算跟踪器
+func NewErrorBudgetTracker(breaker *Breaker, limiter *Limiter, configs ...*ErrorBudgetConfig) *ErrorBudgetTracker {
+	t := &ErrorBudgetTracker{
+		Breaker: breaker,
+		Limiter: limiter,
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+	for _, c := range configs {
+		t.budgets = append(t.budgets, &serviceBudget{config: c, firing: make(map[int]bool)})
+	}
+
+	return t
+}
+
+// 最近window内的请求数和失败数，采样不足window时按已有的采样计算
+func (b *serviceBudget) window(now time.Time, window time.Duration) (int64, int64) {
+	if len(b.samples) == 0 {
+		return 0, 0
+	}
+	last := b.samples[len(b.samples)-1]
+	i := sort.Search(len(b.samples), func(i int) bool { return !b.samples[i].time.Before(now.Add(-window)) })
+	if i == len(b.samples) {
+		i--
+	}
+	first := b.samples[i]
+
+	return last.requests - first.requests, last.failures - first.failures
+}
+
+// 最近window内的错误预算消耗速率
+func (b *serviceBudget) burnRate(now time.Time, window time.Duration) float64 {
+	requests, failures := b.window(now, window)
+	budget := 1 - b.config.Objective
+	if requests == 0 || budget <= 0 {
+		return 0
+	}
+
+	return float64(failures) / float64(requests) / budget
+}
+
+// 周期内剩余的错误预算比例
+func (b *serviceBudget) remaining(now time.Time) float64 {
+	requests, failures := b.window(now, time.Duration(b.config.Period)*time.Second)
+	allowed := (1 - b.config.Objective) * float64(requests)
+	if allowed <= 0 {
+		return 1
+	}
+
+	return 1 - float64(failures)/allowed
+}
+
+// 采样调用统计并检查告警和限流
+func (t *ErrorBudgetTracker) Evaluate(ctx context.Context) {
+	now := time.Now()
+	stats := t.Breaker.ServiceStats()
+
+	var events []BurnEvent
+	t.Lock()
+	for _, b := range t.budgets {
+		s := stats[b.config.Service]
+		b.samples = append(b.samples, budgetSample{time: now, requests: s.Requests, failures: s.Failures})
+		// 保留一个早于周期开始的采样作为周期内增量的基准
+		period := time.Duration(b.config.Period) * time.Second
+		i := sort.Search(len(b.samples), func(i int) bool { return !b.samples[i].time.Before(now.Add(-period)) })
+		if i > 0 {
+			b.samples = append(b.samples[:0:0], b.samples[i-1:]...)
+		}
+
+		remaining := b.remaining(now)
+		for i, alert := range b.config.Alerts {
+			long := b.burnRate(now, time.Duration(alert.Long)*time.Second)
+			short := b.burnRate(now, time.Duration(alert.Short)*time.Second)
+			firing := long >= alert.Threshold && short >= alert.Threshold
+			if firing == b.firing[i] {
+				continue
+			}
+			b.firing[i] = firing
+			events = append(events, BurnEvent{
+				Time:            now,
+				Service:         b.config.Service,
+				Severity:        alert.Severity,
+				Long:            alert.Long,
+				Short:           alert.Short,
+				Threshold:       alert.Threshold,
+				LongBurnRate:    long,
+				ShortBurnRate:   short,
+				BudgetRemaining: remaining,
+				Resolved:        !firing,
+			})
+		}
+		t.tighten(b, remaining)
+	}
+	t.Unlock()
+
+	for _, e := range events {
+		t.emit(ctx, e)
+	}
+}
+
+// 按剩余错误预算收紧或恢复服务的限流，调用方需持有锁
+func (t *ErrorBudgetTracker) tighten(b *serviceBudget, remaining float64) {
+	if t.Limiter == nil || b.config.TightenBelow <= 0 {
+		return
+	}
+
+	tighten := remaining < b.config.TightenBelow
+	if tighten == b.tightened {
+		return
+	}
+	b.tightened = tighten
+	scale, decision := 1.0, "restore"
+	if tighten {
+		scale, decision = b.config.TightenScale, "tighten"
+	}
+	t.Limiter.Throttle(b.config.Service, scale, time.Time{})
+	logDecision(context.Background(), LogLimiter, slog.LevelWarn, "error budget limiter adjusted", b.config.Service, "budget", decision,
+		slog.Float64("budget_remaining", remaining), slog.Float64("scale", scale))
+}
+
+// 分发告警事件
+func (t *ErrorBudgetTracker) emit(ctx context.Context, e BurnEvent) {
+	level, msg := slog.LevelWarn, "error budget burn rate exceeded"
+	if e.Resolved {
+		level, msg = slog.LevelInfo, "error budget burn rate recovered"
+	}
+	logDecision(ctx, LogBreaker, level, msg, e.Service, e.Severity, "alert",
+		slog.Float64("long_burn_rate", e.LongBurnRate), slog.Float64("short_burn_rate", e.ShortBurnRate),
+		slog.Float64("budget_remaining", e.BudgetRemaining))
+
+	if t.OnBurn != nil {
+		t.OnBurn(e)
+	}
+	if t.Webhook != "" {
+		if err := t.post(ctx, e); err != nil {
+			logDecision(ctx, LogReport, slog.LevelWarn, "burn alert webhook failed", e.Service, e.Severity, "drop",
+				slog.String("webhook", t.Webhook), slog.String("error", err.Error()))
+			if t.OnWebhookError != nil {
+				t.OnWebhookError(e, err)
+			}
+		}
+	}
+}
+
+// 将告警事件POST到Webhook
+func (t *ErrorBudgetTracker) post(ctx context.Context, e BurnEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("governance: burn alert webhook rejected with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// 各服务的错误预算状态
+func (t *ErrorBudgetTracker) Status() []ErrorBudgetStatus {
+	now := time.Now()
+	t.Lock()
+	defer t.Unlock()
+
+	statuses := make([]ErrorBudgetStatus, 0, len(t.budgets))
+	for _, b := range t.budgets {
+		requests, failures := b.window(now, time.Duration(b.config.Period)*time.Second)
+		s := ErrorBudgetStatus{
+			Service:         b.config.Service,
+			Objective:       b.config.Objective,
+			Requests:        requests,
+			Failures:        failures,
+			BudgetRemaining: b.remaining(now),
+			BurnRates:       make(map[string]float64),
+			Tightened:       b.tightened,
+		}
+		for i, alert := range b.config.Alerts {
+			for _, w := range []int64{alert.Long, alert.Short} {
+				d := time.Duration(w) * time.Second
+				s.BurnRates[d.String()] = b.burnRate(now, d)
+			}
+			if b.firing[i] {
+				s.Firing = append(s.Firing, alert.Severity)
+			}
+		}
+		statuses = append(statuses, s)
+	}
+
+	return statuses
+}
+
+// 采样间隔的默认值
+const defaultBudgetInterval = 10 * time.Second
+
+// 定时采样并检查，interval不大于0时使用默认值10秒，ctx结束时返回
+func (t *ErrorBudgetTracker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultBudgetInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.Evaluate(ctx)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package governance
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 熔断状态变更的原因，熔断打开的原因见TripFailThreshold等
+const (
+	ReasonOpenTimeout    = "open timeout elapsed"   // 熔断打开时间到期
+	ReasonSuccThreshold  = "succ threshold reached" // 半打开状态下成功次数达到阈值
+	ReasonForced         = "forced"                 // 被强制设置
+	ReasonGaugeRecovered = "gauge recovered"        // 外部指标回落到恢复阈值以下
+)
+
+// 待分发的状态变更队列长度，队列满时丢弃新的状态变更
+const stateChangeQueueSize = 1024
+
+// 熔断状态变更
+type StateChange struct {
+	Resource   string
+	From       BreakerStatus
+	To         BreakerStatus
+	Reason     string
+	Time       time.Time
+	Suppressed int // 合并通知时，本条通知之前被抑制的状态变更数
+}
+
+// 熔断状态变更监听器，在独立的goroutine中按变更顺序调用
+type StateListener func(change StateChange)
+
+// 添加熔断状态变更监听器，首次添加时启动分发goroutine
+func (breaker *Breaker) Subscribe(l StateListener) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	breaker.listeners = append(breaker.listeners, l)
+	if breaker.events == nil {
+		breaker.events = make(chan StateChange, stateChangeQueueSize)
+		go breaker.dispatch()
+	}
+}
+
+// 记录rpc资源r的状态变更，调用方需持有锁
+func (breaker *Breaker) emit(r string, from, to BreakerStatus, reason string) {
+	atomic.AddUint64(&breaker.gen, 1)
+	logDecision(context.Background(), LogBreaker, slog.LevelInfo, "breaker state changed", r, statusName(to), "transition",
+		slog.String("from", statusName(from)), slog.String("reason", reason))
+
+	if breaker.events == nil {
+		return
+	}
+
+	select {
+	case breaker.events <- StateChange{Resource: r, From: from, To: to, Reason: reason, Time: breaker.now()}:
+	default:
+	}
+}
+
+// 将状态变更分发给监听器
+func (breaker *Breaker) dispatch() {
+	for change := range breaker.events {
+		breaker.RLock()
+		listeners := breaker.listeners
+		breaker.RUnlock()
+
+		for _, l := range listeners {
+			l(change)
+		}
+	}
+}
+
+// 合并状态变更通知的时间窗口
+type debounceWindow struct {
+	start      time.Time
+	count      int
+	suppressed int
+	last       StateChange
+	timer      *time.Timer
+}
+
+// 状态变更通知合并器
+type debouncer struct {
+	listener StateListener
+	interval time.Duration
+	max      int
+
+	sync.Mutex
+	windows map[string]*debounceWindow
+}
+
+// 合并频繁的状态变更通知，每个rpc资源在interval内最多通知max次，
+// 超出的变更在窗口结束时合并为一条通知，Suppressed为被抑制的变更数
+func Debounce(l StateListener, interval time.Duration, max int) StateListener {
+	d := &debouncer{
+		listener: l,
+		interval: interval,
+		max:      max,
+		windows:  make(map[string]*debounceWindow),
+	}
+
+	return d.handle
+}
+
+func (d *debouncer) handle(change StateChange) {
+	d.Lock()
+	now := time.Now()
+	w, ok := d.windows[change.Resource]
+	if !ok || now.Sub(w.start) >= d.interval {
+		w = &debounceWindow{start: now}
+		d.windows[change.Resource] = w
+	}
+
+	w.count++
+	if w.count <= d.max {
+		d.Unlock()
+		d.listener(change)
+		return
+	}
+
+	w.suppressed++
+	w.last = change
+	if w.timer == nil {
+		w.timer = time.AfterFunc(d.interval-now.Sub(w.start), func() {
+			d.flush(change.Resource, w)
+		})
+	}
+	d.Unlock()
+}
+
+// 窗口结束时发送合并通知
+func (d *debouncer) flush(r string, w *debounceWindow) {
+	d.Lock()
+	if d.windows[r] == w {
+		delete(d.windows, r)
+	}
+	summary := w.last
+	summary.Suppressed = w.suppressed
+	d.Unlock()
+
+	d.listener(summary)
+}
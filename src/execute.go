@@ -0,0 +1,240 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// rpc资源处于熔断打开状态时返回的错误
+var ErrBreakerOpen = errors.New("governance: breaker is open")
+
+// 调用发生panic时返回的错误
+type PanicError struct {
+	Value interface{} // panic的值
+	Stack []byte      // panic时的调用栈
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("governance: panic recovered: %v\n%s", e.Value, e.Stack)
+}
+
+// 判断rpc资源r当前是否允许调用，会计入拒绝统计并可能占用半打开状态的探测机会，只查询时使用State或Available
+func (breaker *Breaker) Allow(r string) bool {
+	return breaker.allow(context.Background(), breaker.resource(r))
+}
+
+// rpc资源r当前的熔断状态，只查询不产生副作用，不触发OnReject、试运行统计和影子策略比较
+func (breaker *Breaker) State(r string) BreakerStatus {
+	if breaker.Guard != nil {
+		r = breaker.Guard.lookup(r)
+	}
+
+	return breaker.getStatus(r)
+}
+
+// 判断rpc资源r当前是否可用，即熔断未打开或已关闭熔断，只查询不产生副作用，用于路由和选择实例
+// 半打开状态视为可用，是否作为探测请求放行由实际调用时决定
+func (breaker *Breaker) Available(r string) bool {
+	if breaker.Guard != nil {
+		r = breaker.Guard.lookup(r)
+	}
+
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	if breaker.status(r) != OpenStatus {
+		return true
+	}
+	config := breaker.configOf(r)
+
+	return breaker.AllDisabled() || breaker.disabled[r] || config.disabled() || config.DryRun
+}
+
+// 经过基数保护后rpc资源r实际使用的名称
+func (breaker *Breaker) resource(r string) string {
+	if breaker.Guard == nil {
+		return r
+	}
+
+	return breaker.Guard.Guard(r)
+}
+
+// 判断请求ctx当前是否允许调用rpc资源r
+func (breaker *Breaker) allow(ctx context.Context, r string) bool {
+	if o, ok := overridesFrom(ctx); ok && o.Force == ForcePass {
+		return true
+	}
+	cache, cached := decisionCacheFrom(ctx)
+	if cached {
+		if allowed, ok := cache.get(breaker, r); ok {
+			return allowed
+		}
+	}
+
+	s := breaker.snapshot(r)
+	config, status := s.config, s.status
+	disabled := breaker.AllDisabled() || s.disabled
+
+	if breaker.lazy && status == HalfOpenStatus {
+		breaker.lazyHalfOpen(r)
+	}
+
+	allowed := disabled || breaker.decide(ctx, r, status)
+	breaker.compareShadow(ctx, r, allowed)
+	if !allowed && config.DryRun {
+		breaker.recordDryRun(r)
+		// 试运行模式下每次调用都可能命中，按Debug记录，统计见DryRunRejects
+		logDecision(ctx, LogBreaker, slog.LevelDebug, "call would be rejected", r, statusName(status), "dry-run")
+		allowed = true
+	} else if !allowed {
+		logDecision(ctx, LogBreaker, slog.LevelDebug, "call rejected", r, statusName(status), "reject")
+		if breaker.OnReject != nil {
+			breaker.OnReject(r, breaker.rejectReason(r, status))
+		}
+	}
+	if cached && status != HalfOpenStatus {
+		cache.set(breaker, r, allowed)
+	}
+
+	return allowed
+}
+
+// allow使用的rpc资源状态快照，创建后不再修改
+type allowSnapshot struct {
+	gen      uint64
+	config   *Config
+	status   BreakerStatus
+	disabled bool      // 是否由Disable或配置关闭了熔断，不包括DisableAll
+	until    time.Time // 快照的过期时间，惰性熔断器的打开状态在到期后转为半打开，为零值时不过期
+}
+
+// 获取rpc资源r的配置和熔断状态，快照创建后熔断器没有释放过写锁或变更过熔断状态时直接使用快照，不加锁
+func (breaker *Breaker) snapshot(r string) *allowSnapshot {
+	if v, ok := breaker.snapshots.Load(r); ok {
+		if s := v.(*allowSnapshot); s.gen == atomic.LoadUint64(&breaker.gen) && (s.until.IsZero() || breaker.now().Before(s.until)) {
+			return s
+		}
+	}
+
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	s := &allowSnapshot{gen: atomic.LoadUint64(&breaker.gen), config: breaker.configOf(r), status: breaker.status(r)}
+	s.disabled = breaker.disabled[r] || s.config.disabled()
+	// 惰性熔断器的打开状态缓存到打开到期，发布模式的配置会随时间到期，不缓存
+	if breaker.lazy && s.status == OpenStatus {
+		s.until = breaker.R[r].openDeadline()
+	}
+	if len(breaker.deploys) == 0 {
+		breaker.snapshots.Store(r, s)
+	}
+
+	return s
+}
+
+// rpc资源r在status状态下拒绝调用的原因
+func (breaker *Breaker) rejectReason(r string, status BreakerStatus) string {
+	if status == HalfOpenStatus {
+		return "half-open probe not selected"
+	}
+
+	breaker.RLock()
+	defer breaker.RUnlock()
+	if s, ok := breaker.stats[r]; ok && s.TripReason != "" {
+		return "open: " + s.TripReason
+	}
+
+	return "open"
+}
+
+// 根据熔断状态判断请求ctx是否允许调用rpc资源r
+func (breaker *Breaker) decide(ctx context.Context, r string, status BreakerStatus) bool {
+	switch status {
+	case OpenStatus:
+		return false
+	case HalfOpenStatus:
+		if breaker.ProbeSelector != nil {
+			return breaker.ProbeSelector(ctx, r)
+		}
+	}
+
+	return true
+}
+
+// 在熔断器保护下调用rpc资源r，并根据调用结果更新熔断状态
+func (breaker *Breaker) Execute(ctx context.Context, r string, fn func(ctx context.Context) error) error {
+	r = breaker.resource(r)
+	if !breaker.allow(ctx, r) {
+		return ErrBreakerOpen
+	}
+
+	inflight := breaker.inflightOf(r)
+	atomic.AddInt64(inflight, 1)
+	defer atomic.AddInt64(inflight, -1)
+	start := time.Now()
+	watched := breaker.Watchdog.watch(r)
+	// 配置了RePanic时，panic在记录完本次调用结果后重新抛出，与返回错误的调用一样只计入一次
+	rePanic, err := breaker.call(ctx, r, fn)
+	elapsed := time.Since(start)
+	if b, ok := BudgetFrom(ctx); ok {
+		b.Observe(elapsed)
+	}
+	if breaker.Watchdog.done(watched) {
+		// 看门狗已将本次调用计为失败
+		breaker.windowOf(r).record(breaker.now(), elapsed, true)
+	} else {
+		breaker.recordOutcome(ctx, r, err, elapsed)
+	}
+	if rePanic != nil {
+		panic(rePanic)
+	}
+
+	return err
+}
+
+// 记录rpc资源r一次调用的结果
+func (breaker *Breaker) recordOutcome(ctx context.Context, r string, err error, elapsed time.Duration) {
+	outcome := breaker.classify(r, err)
+	breaker.windowOf(r).record(breaker.now(), elapsed, outcome == OutcomeFailure)
+	breaker.recordShadow(r, outcome, elapsed)
+	switch outcome {
+	case OutcomeFailure:
+		breaker.forgetDecision(ctx, r)
+		breaker.setFail(r, err)
+	case OutcomeSuccess:
+		breaker.setSucc(r)
+	}
+}
+
+// 调用fn，将fn中发生的panic转换为PanicError，配置了RePanic时同时返回需要重新抛出的panic值
+func (breaker *Breaker) call(ctx context.Context, r string, fn func(ctx context.Context) error) (rePanic interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+			breaker.RLock()
+			if breaker.configOf(r).RePanic {
+				rePanic = v
+			}
+			breaker.RUnlock()
+		}
+	}()
+
+	return nil, fn(ctx)
+}
+
+// 在熔断器保护下调用rpc资源r，并返回调用结果
+func Do[T any](ctx context.Context, breaker *Breaker, r string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := breaker.Execute(ctx, r, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,174 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAllowSnapshotInvalidation(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 1
+	config.OpenTimeout = time.Hour
+	breaker := newBreaker(&config)
+	errFail := errors.New("fail")
+
+	steps := []struct {
+		name  string
+		apply func()
+		want  bool
+	}{
+		{"closed", func() {}, true},
+		{"tripped", func() { breaker.setFail("r", errFail) }, false},
+		{"disabled", func() { breaker.Disable("r") }, true},
+		{"enabled", func() { breaker.Enable("r") }, false},
+		{"config disabled", func() { breaker.SetConfig("r", &Config{Disabled: Bool(true)}) }, true},
+		{"config removed", func() { breaker.SetConfig("r", nil) }, false},
+		{"forced close", func() { breaker.ForceState("r", CloseStatus) }, true},
+	}
+	for _, step := range steps {
+		step.apply()
+		// 连续判断两次，第二次使用快照
+		for i := 0; i < 2; i++ {
+			if got := breaker.Allow("r"); got != step.want {
+				t.Fatalf("%s: Allow = %v, want %v", step.name, got, step.want)
+			}
+		}
+	}
+}
+
+func TestExecuteRePanicRecordsOnce(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 100
+	config.RePanic = true
+	config.WatchdogTimeout = 1000
+	breaker := newBreaker(&config)
+	breaker.Watchdog = NewWatchdog(breaker, nil)
+
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Errorf("recovered %v, want boom", v)
+			}
+		}()
+		breaker.Execute(context.Background(), "r", func(ctx context.Context) error {
+			panic("boom")
+		})
+	}()
+
+	if s := breaker.Stats()["r"]; s.Requests != 1 || s.Failures != 1 {
+		t.Errorf("stats = %+v, want 1 request and 1 failure", s)
+	}
+	if n := breaker.Requests("r", time.Minute); n != 1 {
+		t.Errorf("window requests = %d, want 1", n)
+	}
+	if n := breaker.InFlight()["r"]; n != 0 {
+		t.Errorf("in-flight = %d, want 0", n)
+	}
+	if n := len(breaker.Watchdog.calls); n != 0 {
+		t.Errorf("watchdog still watching %d calls", n)
+	}
+}
+
+func BenchmarkExecute(b *testing.B) {
+	ctx := context.Background()
+	succ := func(ctx context.Context) error { return nil }
+	errFail := errors.New("fail")
+	fail := func(ctx context.Context) error { return errFail }
+
+	b.Run("success", func(b *testing.B) {
+		config := DefaultConfig()
+		breaker := InitLazyBreaker(&config)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			breaker.Execute(ctx, "bench", succ)
+		}
+	})
+	b.Run("failure", func(b *testing.B) {
+		config := DefaultConfig()
+		config.FailThreshold = 1 << 30
+		breaker := InitLazyBreaker(&config)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			breaker.Execute(ctx, "bench", fail)
+		}
+	})
+	b.Run("open", func(b *testing.B) {
+		config := DefaultConfig()
+		config.FailThreshold = 1
+		breaker := InitLazyBreaker(&config)
+		breaker.Execute(ctx, "bench", fail)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			breaker.Execute(ctx, "bench", succ)
+		}
+	})
+	b.Run("watchdog", func(b *testing.B) {
+		config := DefaultConfig()
+		config.WatchdogTimeout = 1000
+		breaker := InitLazyBreaker(&config)
+		breaker.Watchdog = NewWatchdog(breaker, nil)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			breaker.Execute(ctx, "bench", succ)
+		}
+	})
+	b.Run("shadow", func(b *testing.B) {
+		config := DefaultConfig()
+		breaker := InitLazyBreaker(&config)
+		shadowConfig := DefaultConfig()
+		breaker.SetShadow("bench", BreakerAsStrategy{Breaker: InitLazyBreaker(&shadowConfig)})
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			breaker.Execute(ctx, "bench", succ)
+		}
+	})
+}
+
+func BenchmarkAllow(b *testing.B) {
+	config := DefaultConfig()
+	breaker := InitLazyBreaker(&config)
+	breaker.SetConfig("bench", &Config{FailThreshold: 10})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			breaker.Allow("bench")
+		}
+	})
+}
+
+// 只读取状态的操作不使状态快照失效
+func TestReadsKeepAllowSnapshot(t *testing.T) {
+	config := DefaultConfig()
+	breaker := newBreaker(&config)
+	breaker.Allow("r")
+	gen := atomic.LoadUint64(&breaker.gen)
+
+	breaker.DebugInfo()
+	if got := atomic.LoadUint64(&breaker.gen); got != gen {
+		t.Fatalf("DebugInfo changed generation from %d to %d", gen, got)
+	}
+}
+
+// 试运行模式的拒绝计数和惰性熔断器对半打开状态的检查不使状态快照失效
+func TestHotPathKeepsAllowSnapshot(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 1
+	config.OpenTimeout = time.Millisecond
+	breaker := InitLazyBreaker(&config)
+	breaker.setFail("r", errors.New("fail"))
+	time.Sleep(2 * time.Millisecond)
+	breaker.Allow("r")
+	if breaker.State("r") != HalfOpenStatus {
+		t.Fatalf("state = %v, want half-open", breaker.State("r"))
+	}
+	gen := atomic.LoadUint64(&breaker.gen)
+	breaker.lazyHalfOpen("r")
+	breaker.recordDryRun("r")
+	if got := atomic.LoadUint64(&breaker.gen); got != gen {
+		t.Fatalf("generation changed from %d to %d", gen, got)
+	}
+}
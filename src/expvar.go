@@ -0,0 +1,46 @@
+package governance
+
+import (
+	"expvar"
+)
+
+// 以name发布熔断器的状态和调用统计到expvar，可通过/debug/vars获取，同一name只能发布一次
+func PublishExpvar(name string, breaker *Breaker) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		resources := make(map[string]ResourceState)
+		for _, s := range breaker.Snapshot() {
+			resources[s.Resource] = s
+		}
+
+		return map[string]interface{}{
+			"resources": resources,
+			"services":  breaker.ServiceStats(),
+			"inflight":  breaker.InFlight(),
+			"shadow":    breaker.ShadowStats(),
+		}
+	}))
+}
+
+// 以name发布容量估计到expvar
+func PublishCapacityExpvar(name string, estimator *CapacityEstimator) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return estimator.Estimates()
+	}))
+}
+
+// 以name发布各可用区的往返时延到expvar
+func PublishZoneExpvar(name string, z *ZoneInstances) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return z.RTTs()
+	}))
+}
+
+// 以name发布限流器的等待时间分布和试运行统计到expvar
+func PublishLimiterExpvar(name string, limiter *Limiter) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return map[string]interface{}{
+			"wait":            limiter.WaitStats(),
+			"dry_run_rejects": limiter.DryRunRejects(),
+		}
+	}))
+}
@@ -0,0 +1,72 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// 备集群达到容量上限时返回的错误
+var ErrFailoverCapacity = errors.New("governance: failover capacity exceeded")
+
+// 多集群故障转移配置
+type FailoverConfig struct {
+	Primary           string `toml:"primary"`            // 主集群
+	Secondary         string `toml:"secondary"`          // 备集群
+	SecondaryCapacity int64  `toml:"secondary_capacity"` // 转移到备集群的最大并发数，0表示不限制
+}
+
+// 多集群故障转移，主集群熔断打开或无健康实例时转移到备集群，主集群恢复后自动切回
+type Failover struct {
+	Config  *FailoverConfig
+	Breaker *Breaker                  // 以集群名作为rpc资源名的熔断器
+	Healthy func(cluster string) bool // 集群是否有健康实例，为空时只根据熔断状态判断
+
+	inflight int64 // 备集群当前并发数
+}
+
+// 创建多集群故障转移
+func NewFailover(config *FailoverConfig, breaker *Breaker) *Failover {
+	return &Failover{
+		Config:  config,
+		Breaker: breaker,
+	}
+}
+
+// 调用集群，fn的cluster参数为本次调用的集群
+func (f *Failover) Execute(ctx context.Context, fn func(ctx context.Context, cluster string) error) error {
+	primary := f.Config.Primary
+	if f.available(primary) {
+		called := false
+		err := f.Breaker.Execute(ctx, primary, func(ctx context.Context) error {
+			called = true
+			return fn(ctx, primary)
+		})
+		// 半打开状态下探测名额已满时主集群拒绝调用，同样转移到备集群
+		if called || !errors.Is(err, ErrBreakerOpen) {
+			return err
+		}
+	}
+
+	secondary := f.Config.Secondary
+	if f.Config.SecondaryCapacity > 0 {
+		if atomic.AddInt64(&f.inflight, 1) > f.Config.SecondaryCapacity {
+			atomic.AddInt64(&f.inflight, -1)
+			return ErrFailoverCapacity
+		}
+		defer atomic.AddInt64(&f.inflight, -1)
+	}
+
+	return f.Breaker.Execute(ctx, secondary, func(ctx context.Context) error {
+		return fn(ctx, secondary)
+	})
+}
+
+// 判断集群是否可用，只查询状态，熔断判断由Execute进行一次
+func (f *Failover) available(cluster string) bool {
+	if f.Healthy != nil && !f.Healthy(cluster) {
+		return false
+	}
+
+	return f.Breaker.Available(cluster)
+}
@@ -0,0 +1,43 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 主集群熔断时转移到备集群，不触发主集群的拒绝回调，恢复后切回
+func TestFailoverUsesStateCheck(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 1
+	config.OpenTimeout = time.Hour
+	breaker := newBreaker(&config)
+	rejects := 0
+	breaker.OnReject = func(r, reason string) {
+		rejects++
+	}
+	f := NewFailover(&FailoverConfig{Primary: "a", Secondary: "b"}, breaker)
+
+	var clusters []string
+	call := func(err error) {
+		f.Execute(context.Background(), func(ctx context.Context, cluster string) error {
+			clusters = append(clusters, cluster)
+			return err
+		})
+	}
+	call(errors.New("fail"))
+	call(nil)
+	if len(clusters) != 2 || clusters[0] != "a" || clusters[1] != "b" {
+		t.Fatalf("clusters = %v, want [a b]", clusters)
+	}
+	if rejects != 0 {
+		t.Fatalf("OnReject called %d times, want 0", rejects)
+	}
+
+	breaker.ForceState("a", CloseStatus)
+	call(nil)
+	if clusters[2] != "a" {
+		t.Fatalf("clusters = %v, want primary after recovery", clusters)
+	}
+}
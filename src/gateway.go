@@ -0,0 +1,185 @@
+package governance
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// REST路由到gRPC方法的映射，路径为google.api.http注解的路径模板，如/v1/{name=shelves/*}/books
+type GatewayRoute struct {
+	Method     string `toml:"method"`      // http方法，如GET
+	Path       string `toml:"path"`        // 路径模板
+	GRPCMethod string `toml:"grpc_method"` // gRPC方法全名，如/pkg.Service/Method
+}
+
+// 路径模板段的类型
+const (
+	segLiteral = iota // 字面量
+	segAny            // *，匹配一段
+	segDeep           // **，匹配剩余的零或多段
+)
+
+type pathSegment struct {
+	kind    int
+	literal string
+}
+
+// 编译后的路由
+type gatewayRoute struct {
+	method     string
+	segments   []pathSegment
+	verb       string // 自定义方法后缀，如:cancel中的cancel
+	grpcMethod string
+}
+
+// REST到gRPC的路径映射，使同一方法无论经gRPC还是gRPC-Gateway的REST路由到达，都使用相同的rpc资源名，统计和规则保持一致
+type GatewayMapper struct {
+	sync.RWMutex
+	routes []*gatewayRoute
+}
+
+// 创建路径映射
+func NewGatewayMapper(routes ...GatewayRoute) (*GatewayMapper, error) {
+	m := &GatewayMapper{}
+	for _, r := range routes {
+		if err := m.Add(r.Method, r.Path, r.GRPCMethod); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// 添加httpMethod请求path模板到gRPC方法grpcMethod的映射，多个模板都匹配时使用先添加的
+func (m *GatewayMapper) Add(httpMethod, path, grpcMethod string) error {
+	route, err := parsePathTemplate(path)
+	if err != nil {
+		return err
+	}
+	route.method = strings.ToUpper(httpMethod)
+	route.grpcMethod = NormalizeGRPCMethod(grpcMethod)
+
+	m.Lock()
+	m.routes = append(m.routes, route)
+	m.Unlock()
+
+	return nil
+}
+
+// 解析路径模板，变量按其匹配的段展开，变量名忽略
+func parsePathTemplate(path string) (*gatewayRoute, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("governance: path template %q must start with /", path)
+	}
+
+	route := &gatewayRoute{}
+	tpl := path[1:]
+	// 自定义方法后缀只能出现在最后一个变量之后
+	if i := strings.LastIndexByte(tpl, ':'); i >= 0 && i > strings.LastIndexByte(tpl, '}') {
+		tpl, route.verb = tpl[:i], tpl[i+1:]
+	}
+
+	for tpl != "" {
+		var seg string
+		if strings.HasPrefix(tpl, "{") {
+			end := strings.IndexByte(tpl, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("governance: unclosed variable in path template %q", path)
+			}
+			seg, tpl = tpl[1:end], tpl[end+1:]
+			sub := "*"
+			if i := strings.IndexByte(seg, '='); i >= 0 {
+				sub = seg[i+1:]
+			}
+			for _, s := range strings.Split(sub, "/") {
+				route.segments = append(route.segments, templateSegment(s))
+			}
+		} else {
+			end := strings.IndexByte(tpl, '/')
+			if end < 0 {
+				end = len(tpl)
+			}
+			seg, tpl = tpl[:end], tpl[end:]
+			route.segments = append(route.segments, templateSegment(seg))
+		}
+		if strings.HasPrefix(tpl, "/") {
+			tpl = tpl[1:]
+		} else if tpl != "" {
+			return nil, fmt.Errorf("governance: invalid path template %q", path)
+		}
+	}
+
+	return route, nil
+}
+
+func templateSegment(s string) pathSegment {
+	switch s {
+	case "*":
+		return pathSegment{kind: segAny}
+	case "**":
+		return pathSegment{kind: segDeep}
+	}
+
+	return pathSegment{kind: segLiteral, literal: s}
+}
+
+// 判断路径段parts是否匹配模板段segments
+func matchSegments(segments []pathSegment, parts []string) bool {
+	for i, seg := range segments {
+		if seg.kind == segDeep {
+			for j := i; j <= len(parts); j++ {
+				if matchSegments(segments[i+1:], parts[j:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if i >= len(parts) || (seg.kind == segLiteral && seg.literal != parts[i]) {
+			return false
+		}
+	}
+
+	return len(segments) == len(parts)
+}
+
+// 查找httpMethod请求path对应的gRPC方法
+func (m *GatewayMapper) Resolve(httpMethod, path string) (string, bool) {
+	path = strings.TrimPrefix(path, "/")
+	verb := ""
+	if i := strings.LastIndexByte(path, ':'); i >= 0 && i > strings.LastIndexByte(path, '/') {
+		path, verb = path[:i], path[i+1:]
+	}
+	var parts []string
+	if path != "" {
+		parts = strings.Split(path, "/")
+	}
+	httpMethod = strings.ToUpper(httpMethod)
+
+	m.RLock()
+	defer m.RUnlock()
+	for _, route := range m.routes {
+		if route.method == httpMethod && route.verb == verb && matchSegments(route.segments, parts) {
+			return route.grpcMethod, true
+		}
+	}
+
+	return "", false
+}
+
+// 服务service的http请求req对应的rpc资源名，映射到gRPC方法时与GRPCResourceKey相同，否则按http方法和规范化的路径
+func (m *GatewayMapper) ResourceKey(service string, req *http.Request) string {
+	if m != nil {
+		if method, ok := m.Resolve(req.Method, req.URL.Path); ok {
+			return GRPCResourceKey(service, method)
+		}
+	}
+
+	return ResourceKey{Service: service, Method: req.Method + " " + NormalizeHTTPPath(req.URL.Path)}.Encode()
+}
+
+// 服务service的gRPC方法fullMethod对应的rpc资源名
+func GRPCResourceKey(service, fullMethod string) string {
+	return ResourceKey{Service: service, Method: NormalizeGRPCMethod(fullMethod)}.Encode()
+}
@@ -0,0 +1,138 @@
+package governance
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 外部指标来源，如Kafka消费积压、工作队列长度
+type GaugeSource interface {
+	Gauge(ctx context.Context) (float64, error)
+}
+
+// 函数形式的外部指标来源
+type GaugeFunc func(ctx context.Context) (float64, error)
+
+func (f GaugeFunc) Gauge(ctx context.Context) (float64, error) {
+	return f(ctx)
+}
+
+// 按外部指标熔断的规则
+type GaugeRule struct {
+	Resource  string      `toml:"resource"`  // 被熔断的rpc资源，通常为生产者写入的队列或下游
+	Threshold float64     `toml:"threshold"` // 指标达到此值时打开熔断
+	Resume    float64     `toml:"resume"`    // 指标回落到此值以下时转为半打开，为0时与Threshold相同
+	Source    GaugeSource `toml:"-"`
+}
+
+// 按外部指标熔断的控制器，指标超过阈值时打开熔断使生产者停止写入，回落后转为半打开按正常流程探测恢复
+// 指标持续超过阈值期间每次检查都会刷新熔断打开时间，检查间隔需小于熔断打开时间
+type GaugeBreaker struct {
+	Breaker *Breaker
+	Rules   []*GaugeRule
+	OnError func(rule *GaugeRule, err error) // 读取指标失败时调用，失败时保持当前状态
+
+	sync.Mutex
+	tripped map[string]bool // 因指标打开熔断的rpc资源
+	values  map[string]float64
+}
+
+// 创建按外部指标熔断的控制器
+func NewGaugeBreaker(breaker *Breaker, rules ...*GaugeRule) *GaugeBreaker {
+	return &GaugeBreaker{
+		Breaker: breaker,
+		Rules:   rules,
+		tripped: make(map[string]bool),
+		values:  make(map[string]float64),
+	}
+}
+
+// 读取所有指标并更新熔断状态
+func (g *GaugeBreaker) Evaluate(ctx context.Context) {
+	for _, rule := range g.Rules {
+		v, err := rule.Source.Gauge(ctx)
+		if err != nil {
+			if g.OnError != nil {
+				g.OnError(rule, err)
+			}
+			continue
+		}
+
+		resume := rule.Resume
+		if resume <= 0 {
+			resume = rule.Threshold
+		}
+
+		g.Lock()
+		g.values[rule.Resource] = v
+		tripped := g.tripped[rule.Resource]
+		switch {
+		case v >= rule.Threshold:
+			g.tripped[rule.Resource] = true
+			g.Breaker.tripByGauge(rule.Resource)
+		case tripped && v < resume:
+			delete(g.tripped, rule.Resource)
+			g.Breaker.resumeByGauge(rule.Resource)
+		case tripped:
+			// 指标处于恢复阈值和打开阈值之间，保持打开
+			g.Breaker.tripByGauge(rule.Resource)
+		}
+		g.Unlock()
+	}
+}
+
+// 各rpc资源最近一次读取到的指标值
+func (g *GaugeBreaker) Values() map[string]float64 {
+	g.Lock()
+	defer g.Unlock()
+
+	values := make(map[string]float64, len(g.values))
+	for r, v := range g.values {
+		values[r] = v
+	}
+
+	return values
+}
+
+// 定时检查指标，ctx结束时返回
+func (g *GaugeBreaker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.Evaluate(ctx)
+		}
+	}
+}
+
+// 因外部指标打开rpc资源r的熔断，已打开时刷新打开时间
+func (breaker *Breaker) tripByGauge(r string) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	v := breaker.ensure(r)
+	if v.Status == OpenStatus {
+		now := breaker.now()
+		v.OpenTime, v.openedAt = now.Unix(), now
+		return
+	}
+	from := v.Status
+	setOpenStatus(breaker.configOf(r), v, breaker.now())
+	breaker.trip(r, from, TripGauge)
+}
+
+// 外部指标回落后将rpc资源r的熔断由打开置为半打开
+func (breaker *Breaker) resumeByGauge(r string) {
+	breaker.Lock()
+	defer breaker.Unlock()
+
+	if v, ok := breaker.R[r]; ok && v.Status == OpenStatus {
+		setHalfOpenStatus(v)
+		breaker.emit(r, OpenStatus, HalfOpenStatus, ReasonGaugeRecovered)
+	}
+}
@@ -0,0 +1,194 @@
+package governance
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 治理总配置，可由配置文件整体加载
+type GovernanceConfig struct {
+	Breaker   Config             `toml:"breaker"`    // 熔断器配置
+	Lazy      bool               `toml:"lazy"`       // 是否使用不启动后台goroutine的熔断器
+	Limiter   *LimiterConfig     `toml:"limiter"`    // 限流器配置，为空时不启用限流
+	Heartbeat *HeartbeatConfig   `toml:"heartbeat"`  // 心跳上报配置，为空时不上报
+	Ramp      int64              `toml:"ramp"`       // 路由权重渐变时长，单位秒
+	AdminAuth *AdminAuthConfig   `toml:"admin_auth"` // 管理接口鉴权配置，为空时不鉴权
+	Guarded   bool               `toml:"guarded"`    // 新规则是否需人工确认后才生效
+	Retry     *RetryConfig       `toml:"retry"`      // 重试配置，为空时不重试
+	Recorder  *RecorderConfig    `toml:"recorder"`   // 飞行记录器配置，为空时不记录
+	Deploy    *DeployModeConfig  `toml:"deploy"`     // 发布模式配置，为空时使用默认值
+	Startup   *StartupRampConfig `toml:"startup"`    // 进程启动预热配置，为空时不预热
+	// rpc资源名或服务名对应关闭的处理阶段，如支付服务关闭retry，本地缓存关闭breaker
+	Stages map[string][]string `toml:"stages"`
+	// 请求类别对应的治理策略，key为read、write、expensive等类别名，请求类别由ClassifyHandler设置
+	Classes map[string]*ClassPolicy `toml:"classes"`
+}
+
+// 默认熔断器配置
+func DefaultConfig() Config {
+	return Config{
+		FailThreshold: 5,
+		SuccThreshold: 2,
+		OpenTimeout:   defaultOpenTimeout,
+	}
+}
+
+// 治理入口，统一创建熔断、限流、路由、规则管理和控制面
+type Governance struct {
+	Config    *GovernanceConfig
+	Breaker   *Breaker
+	Limiter   *Limiter
+	Router    *Router
+	Rules     *RuleManager
+	Control   *ControlPlane
+	Heartbeat *HeartbeatAgent
+	Retry     *Retry
+	Ready     *Readiness // 就绪检查，可添加服务发现和规则加载等检查项，建议挂载在不需要鉴权的ReadinessPath上
+	Recorder  *FlightRecorder
+	Startup   *StartupRamp // 入口流量的启动预热，需通过StartupRampHandler挂载到入口
+	methods   sync.Map     // 方法全名对应的方法级配置，值为*methodState，见ApplyMethodConfigs
+}
+
+// 治理选项
+type Option func(config *GovernanceConfig)
+
+// 使用完整的治理配置
+func WithConfig(c *GovernanceConfig) Option {
+	return func(config *GovernanceConfig) {
+		*config = *c
+	}
+}
+
+// 设置熔断器配置
+func WithBreakerConfig(c Config) Option {
+	return func(config *GovernanceConfig) {
+		config.Breaker = c
+	}
+}
+
+// 使用不启动后台goroutine的熔断器
+func WithLazyBreaker() Option {
+	return func(config *GovernanceConfig) {
+		config.Lazy = true
+	}
+}
+
+// 设置限流器配置
+func WithLimiterConfig(c *LimiterConfig) Option {
+	return func(config *GovernanceConfig) {
+		config.Limiter = c
+	}
+}
+
+// 设置心跳上报配置
+func WithHeartbeat(c *HeartbeatConfig) Option {
+	return func(config *GovernanceConfig) {
+		config.Heartbeat = c
+	}
+}
+
+// 设置重试配置
+func WithRetry(c *RetryConfig) Option {
+	return func(config *GovernanceConfig) {
+		config.Retry = c
+	}
+}
+
+// 关闭rpc资源或服务r的处理阶段stages
+func WithDisabledStages(r string, stages ...string) Option {
+	return func(config *GovernanceConfig) {
+		if config.Stages == nil {
+			config.Stages = make(map[string][]string)
+		}
+		config.Stages[r] = append(config.Stages[r], stages...)
+	}
+}
+
+// 设置路由权重渐变时长
+func WithRamp(d time.Duration) Option {
+	return func(config *GovernanceConfig) {
+		config.Ramp = int64(d / time.Second)
+	}
+}
+
+// 创建治理入口
+func New(opts ...Option) *Governance {
+	config := &GovernanceConfig{Breaker: DefaultConfig()}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	g := &Governance{Config: config}
+	if config.Lazy {
+		g.Breaker = InitLazyBreaker(&config.Breaker)
+	} else {
+		g.Breaker = InitBreaker(&config.Breaker)
+	}
+	if config.Limiter != nil {
+		g.Limiter = NewLimiter(config.Limiter)
+	}
+	if config.Retry != nil {
+		g.Retry = NewRetry(config.Retry)
+		g.Retry.Classifier = retryClassifier
+	}
+	g.Breaker.DeployConfig = config.Deploy
+	g.Router = NewRouter(time.Duration(config.Ramp) * time.Second)
+	g.Rules = NewRuleManager(g.Breaker)
+	g.Rules.Guarded = config.Guarded
+	g.Control = NewControlPlane(g.Breaker, g.Rules)
+	g.Ready = NewReadiness()
+	g.Ready.Add("breaker", BreakerReady(g.Breaker))
+	if config.Heartbeat != nil {
+		g.Heartbeat = NewHeartbeatAgent(config.Heartbeat, g.Control)
+	}
+	if config.Startup != nil {
+		g.Startup = NewStartupRamp(config.Startup)
+	}
+	if config.Recorder != nil {
+		g.Recorder = NewFlightRecorder(g.Breaker, time.Duration(config.Recorder.Window)*time.Second,
+			time.Duration(config.Recorder.Interval)*time.Second)
+		g.Recorder.Dir = config.Recorder.Dir
+	}
+
+	return g
+}
+
+// 在限流、熔断和重试保护下调用rpc资源r，限流以r作为调用方，按WithCost声明的代价扣减令牌
+// 每次重试都重新经过限流和熔断，各处理阶段可按rpc资源或请求类别关闭，见GovernanceConfig.Stages和Classes
+func (g *Governance) Execute(ctx context.Context, r string, fn func(ctx context.Context) error) error {
+	ctx, r, disabled := g.applyClass(ctx, r)
+	ctx, cancel := g.methodTimeout(ctx, r)
+	defer cancel()
+	retry := g.retryFor(r)
+	if retry == nil || retryDisabled(ctx) || !g.stageEnabled(r, StageRetry, disabled) {
+		return g.executeStages(ctx, r, disabled, fn)
+	}
+
+	return retry.Do(ctx, func(ctx context.Context) error {
+		return g.executeStages(ctx, r, disabled, fn)
+	})
+}
+
+// 管理接口和调试接口，配置了鉴权时需通过鉴权才能访问
+func (g *Governance) AdminHandler() http.Handler {
+	admin := NewAdminHandler(g.Control)
+	mux := http.NewServeMux()
+	mux.Handle(AdminStatsPath, admin)
+	mux.Handle(AdminForcePath, admin)
+	mux.Handle(AdminSchemaPath, admin)
+	mux.Handle(AdminPendingPath, admin)
+	mux.Handle(AdminConfirmPath, admin)
+	mux.Handle(AdminDeployPath, admin)
+	RegisterDebugHandlers(mux, g.Breaker)
+	if g.Recorder != nil {
+		mux.Handle(DebugRecorderPath, g.Recorder)
+	}
+
+	if g.Config.AdminAuth != nil {
+		return RequireAdminAuth(mux, g.Config.AdminAuth)
+	}
+
+	return mux
+}
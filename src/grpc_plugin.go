@@ -0,0 +1,253 @@
+//go:build grpc
+
+package governance
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/attributes"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC插件的名称，resolver的scheme和balancer的名称
+const GRPCPluginName = "governance"
+
+// 有实例因熔断被移除时重新推送地址的间隔，熔断器为lazy模式时打开到半打开的转换不产生状态变化事件
+const grpcResolveRetryInterval = time.Second
+
+// resolver.Address.BalancerAttributes中保存服务名的key
+type grpcServiceKey struct{}
+
+// 基于StaticInstances的gRPC resolver，目标地址为governance:///service
+// 实例列表变化或实例熔断状态变化时重新推送地址
+type grpcResolverBuilder struct {
+	instances *StaticInstances
+
+	mu        sync.Mutex
+	resolvers map[*grpcResolver]bool
+}
+
+// 注册gRPC resolver和balancer插件，需在创建gRPC连接前调用
+func RegisterGRPCPlugins(instances *StaticInstances) {
+	b := &grpcResolverBuilder{
+		instances: instances,
+		resolvers: make(map[*grpcResolver]bool),
+	}
+	if instances.Breaker != nil {
+		instances.Breaker.Subscribe(b.stateChanged)
+	}
+	resolver.Register(b)
+	balancer.Register(base.NewBalancerBuilder(GRPCPluginName, &p2cPickerBuilder{
+		breaker: instances.Breaker,
+	}, base.Config{HealthCheck: true}))
+}
+
+func (b *grpcResolverBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &grpcResolver{
+		service:   target.Endpoint(),
+		instances: b.instances,
+		cc:        cc,
+		builder:   b,
+	}
+	b.mu.Lock()
+	b.resolvers[r] = true
+	b.mu.Unlock()
+	// 注册监听时立即推送一次当前的实例
+	r.cancel = b.instances.Watch(r.service, func([]string) {
+		r.ResolveNow(resolver.ResolveNowOptions{})
+	})
+
+	return r, nil
+}
+
+// 实例熔断状态变化时，重新推送该服务的地址，熔断打开的实例被移除，恢复后重新加入
+func (b *grpcResolverBuilder) stateChanged(change StateChange) {
+	service := DecodeResourceKey(change.Resource).Service
+	b.mu.Lock()
+	var resolvers []*grpcResolver
+	for r := range b.resolvers {
+		if r.service == service {
+			resolvers = append(resolvers, r)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, r := range resolvers {
+		r.ResolveNow(resolver.ResolveNowOptions{})
+	}
+}
+
+func (b *grpcResolverBuilder) Scheme() string {
+	return GRPCPluginName
+}
+
+type grpcResolver struct {
+	service   string
+	instances *StaticInstances
+	cc        resolver.ClientConn
+	builder   *grpcResolverBuilder
+	cancel    func() // 取消监听实例列表
+
+	mu     sync.Mutex
+	closed bool
+	retry  *time.Timer // 有实例被移除时重新推送地址的定时器
+}
+
+// 将服务的实例列表中未熔断的实例推送给gRPC
+func (r *grpcResolver) ResolveNow(resolver.ResolveNowOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+
+	var addrs []resolver.Address
+	removed := false
+	for _, inst := range r.instances.Instances(r.service) {
+		if r.instances.Breaker != nil && !r.instances.Breaker.Available(ResourceKey{Service: r.service, Instance: inst}.Encode()) {
+			removed = true
+			continue
+		}
+		// ServerName是TLS校验的authority，服务名放在BalancerAttributes中
+		addrs = append(addrs, resolver.Address{Addr: inst, BalancerAttributes: attributes.New(grpcServiceKey{}, r.service)})
+	}
+	r.cc.UpdateState(resolver.State{Addresses: addrs})
+
+	// 被移除的实例在熔断进入半打开后需重新加入
+	if removed && r.retry == nil {
+		r.retry = time.AfterFunc(grpcResolveRetryInterval, func() {
+			r.mu.Lock()
+			r.retry = nil
+			r.mu.Unlock()
+			r.ResolveNow(resolver.ResolveNowOptions{})
+		})
+	}
+}
+
+func (r *grpcResolver) Close() {
+	r.builder.mu.Lock()
+	delete(r.builder.resolvers, r)
+	r.builder.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	r.mu.Lock()
+	r.closed = true
+	if r.retry != nil {
+		r.retry.Stop()
+		r.retry = nil
+	}
+	r.mu.Unlock()
+}
+
+// P2C picker构建器
+type p2cPickerBuilder struct {
+	breaker *Breaker
+}
+
+func (b *p2cPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	p := &p2cPicker{breaker: b.breaker}
+	for sc, scInfo := range info.ReadySCs {
+		service, _ := scInfo.Address.BalancerAttributes.Value(grpcServiceKey{}).(string)
+		p.conns = append(p.conns, &p2cConn{
+			sc:       sc,
+			resource: ResourceKey{Service: service, Instance: scInfo.Address.Addr}.Encode(),
+		})
+	}
+
+	return p
+}
+
+type p2cConn struct {
+	sc       balancer.SubConn
+	resource string
+	inflight int64
+}
+
+// 随机选择两个连接，取进行中请求数较少且未熔断的连接
+type p2cPicker struct {
+	breaker *Breaker
+	conns   []*p2cConn
+
+	mu   sync.Mutex
+	next uint32
+}
+
+func (p *p2cPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	a, b := p.candidates()
+	c := a
+	if b != nil && (p.open(a) || (!p.open(b) && atomic.LoadInt64(&b.inflight) < atomic.LoadInt64(&a.inflight))) {
+		c = b
+	}
+	// 比较候选连接时只查询状态，对选中的连接才计入熔断判断
+	if p.breaker != nil && !p.breaker.Allow(c.resource) {
+		if c = p.fallback(); c == nil {
+			// 返回ErrNoSubConnAvailable会使调用阻塞到picker更新，熔断打开时应立即失败
+			return balancer.PickResult{}, status.Error(codes.Unavailable, "governance: all instances are circuit broken")
+		}
+	}
+
+	atomic.AddInt64(&c.inflight, 1)
+	return balancer.PickResult{
+		SubConn: c.sc,
+		Done: func(info balancer.DoneInfo) {
+			atomic.AddInt64(&c.inflight, -1)
+			if p.breaker == nil {
+				return
+			}
+			switch p.breaker.classify(c.resource, info.Err) {
+			case OutcomeFailure:
+				p.breaker.setFail(c.resource, info.Err)
+			case OutcomeSuccess:
+				p.breaker.setSucc(c.resource)
+			}
+		},
+	}, nil
+}
+
+// 选择两个不同的候选连接
+func (p *p2cPicker) candidates() (*p2cConn, *p2cConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n := uint32(len(p.conns))
+	if n == 1 {
+		return p.conns[0], nil
+	}
+	p.next++
+	i := p.next % n
+	j := (i + 1 + uint32(rand.Intn(int(n-1)))) % n
+
+	return p.conns[i], p.conns[j]
+}
+
+// 两个候选连接都熔断时，从随机位置开始在所有连接中选择一个允许调用的连接，没有时返回空
+func (p *p2cPicker) fallback() *p2cConn {
+	n := len(p.conns)
+	start := rand.Intn(n)
+	for k := 0; k < n; k++ {
+		c := p.conns[(start+k)%n]
+		if !p.open(c) && p.breaker.Allow(c.resource) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// 判断连接对应的实例是否熔断，只查询不产生副作用
+func (p *p2cPicker) open(c *p2cConn) bool {
+	return p.breaker != nil && !p.breaker.Available(c.resource)
+}
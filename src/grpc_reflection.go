@@ -0,0 +1,109 @@
+//go:build grpc
+
+package governance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// 默认跳过的服务，不作为rpc资源注册
+var reflectionSkipServices = map[string]bool{
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+	"grpc.health.v1.Health":                    true,
+}
+
+// 基于gRPC server reflection的方法列表，目标服务需注册reflection服务
+type GRPCReflectionLister struct {
+	Conn grpc.ClientConnInterface
+	Skip map[string]bool // 额外跳过的服务全名，reflection和health服务总是跳过
+}
+
+// 创建基于gRPC server reflection的方法列表
+func NewGRPCReflectionLister(conn grpc.ClientConnInterface) *GRPCReflectionLister {
+	return &GRPCReflectionLister{Conn: conn}
+}
+
+// 返回目标服务所有方法的全名，如/pkg.Service/Method
+func (l *GRPCReflectionLister) ListMethods(ctx context.Context) ([]string, error) {
+	methods, err := l.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(methods))
+	for _, m := range methods {
+		names = append(names, m.FullName())
+	}
+
+	return names, nil
+}
+
+// 通过reflection获取目标服务所有方法的描述，方法上声明的治理option可由LoadMethodConfigs生成配置
+func (l *GRPCReflectionLister) Describe(ctx context.Context) ([]MethodDescriptor, error) {
+	stream, err := rpb.NewServerReflectionClient(l.Conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	resp, err := reflectionRoundTrip(stream, &rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []MethodDescriptor
+	seen := make(map[string]bool)
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		name := svc.GetName()
+		if reflectionSkipServices[name] || l.Skip[name] {
+			continue
+		}
+
+		resp, err := reflectionRoundTrip(stream, &rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: name},
+		})
+		if err != nil {
+			return nil, err
+		}
+		// 响应中包含服务所在文件及其依赖，只保留该服务的方法
+		prefix := "/" + name + "/"
+		for _, file := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			described, err := ParseFileDescriptor(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range described {
+				if strings.HasPrefix(m.FullName(), prefix) && !seen[m.FullName()] {
+					seen[m.FullName()] = true
+					methods = append(methods, m)
+				}
+			}
+		}
+	}
+
+	return methods, nil
+}
+
+// 在reflection流上发送一个请求并等待响应
+func reflectionRoundTrip(stream rpb.ServerReflection_ServerReflectionInfoClient, req *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	if err := stream.Send(req); err != nil {
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, fmt.Errorf("governance: server reflection error %d: %s", e.GetErrorCode(), e.GetErrorMessage())
+	}
+
+	return resp, nil
+}
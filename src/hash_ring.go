@@ -0,0 +1,93 @@
+package governance
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// 一致性哈希环，按请求key将请求固定到实例
+type HashRing struct {
+	Service  string   // 服务名
+	Replicas int      // 每个实例的虚拟节点数
+	Breaker  *Breaker // 实例熔断器，实例的rpc资源名为ResourceKey{Service, Instance}编码
+
+	// 固定的实例熔断打开时是否改为选择环上的下一个实例
+	BreakSticky bool
+	// 打破固定时的通知，为空时不通知
+	OnStickyBreak func(key, pinned, actual string)
+
+	sync.RWMutex
+	hashes []uint32
+	nodes  map[uint32]string
+}
+
+// 创建一致性哈希环
+func NewHashRing(service string, replicas int, breaker *Breaker) *HashRing {
+	return &HashRing{
+		Service:  service,
+		Replicas: replicas,
+		Breaker:  breaker,
+		nodes:    make(map[uint32]string),
+	}
+}
+
+// 设置实例列表
+func (h *HashRing) SetInstances(instances []string) {
+	hashes := make([]uint32, 0, len(instances)*h.Replicas)
+	nodes := make(map[uint32]string, len(instances)*h.Replicas)
+	for _, inst := range instances {
+		for i := 0; i < h.Replicas; i++ {
+			hash := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + inst))
+			hashes = append(hashes, hash)
+			nodes[hash] = inst
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.hashes = hashes
+	h.nodes = nodes
+}
+
+// 为请求key选择实例
+func (h *HashRing) Pick(key string) (string, bool) {
+	h.RLock()
+	defer h.RUnlock()
+
+	if len(h.hashes) == 0 {
+		return "", false
+	}
+
+	hash := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(h.hashes), func(i int) bool { return h.hashes[i] >= hash }) % len(h.hashes)
+	pinned := h.nodes[h.hashes[start]]
+	if !h.BreakSticky || h.available(pinned) {
+		return pinned, true
+	}
+
+	// 沿环查找下一个未熔断的实例
+	for i := 1; i < len(h.hashes); i++ {
+		inst := h.nodes[h.hashes[(start+i)%len(h.hashes)]]
+		if inst != pinned && h.available(inst) {
+			if h.OnStickyBreak != nil {
+				h.OnStickyBreak(key, pinned, inst)
+			}
+			return inst, true
+		}
+	}
+
+	return pinned, true
+}
+
+// 判断实例是否未熔断
+func (h *HashRing) available(inst string) bool {
+	if h.Breaker == nil {
+		return true
+	}
+
+	return h.Breaker.Available(ResourceKey{Service: h.Service, Instance: inst}.Encode())
+}
@@ -0,0 +1,130 @@
+package governance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// 心跳上报配置
+type HeartbeatConfig struct {
+	Endpoint string  `toml:"endpoint"` // 收集端地址
+	Interval int64   `toml:"interval"` // 上报间隔，单位秒，为0时使用默认值10秒
+	Jitter   float64 `toml:"jitter"`   // 上报间隔的随机抖动比例，取值0~1
+	Service  string  `toml:"service"`  // 服务名
+	Instance string  `toml:"instance"` // 实例标识
+}
+
+// 心跳上报间隔的默认值
+const defaultHeartbeatInterval = 10 * time.Second
+
+// 心跳上报间隔
+func (config *HeartbeatConfig) interval() time.Duration {
+	if config.Interval <= 0 {
+		return defaultHeartbeatInterval
+	}
+
+	return time.Duration(config.Interval) * time.Second
+}
+
+// 心跳内容
+type Heartbeat struct {
+	Service     string          `json:"service"`
+	Instance    string          `json:"instance"`
+	Time        int64           `json:"time"`
+	RuleVersion int64           `json:"rule_version"`
+	Resources   []ResourceState `json:"resources"`
+}
+
+// 心跳上报代理，定时向收集端上报实例标识、生效的规则版本和统计摘要
+type HeartbeatAgent struct {
+	Config  *HeartbeatConfig
+	Control *ControlPlane
+	Client  *http.Client
+	OnError func(err error) // Run中上报失败时调用，为空时只记录日志
+
+	failures int64 // Run中累计上报失败的次数
+}
+
+// 创建心跳上报代理
+func NewHeartbeatAgent(config *HeartbeatConfig, control *ControlPlane) *HeartbeatAgent {
+	return &HeartbeatAgent{
+		Config:  config,
+		Control: control,
+		Client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// 上报一次心跳
+func (a *HeartbeatAgent) Report(ctx context.Context) error {
+	hb := Heartbeat{
+		Service:   a.Config.Service,
+		Instance:  a.Config.Instance,
+		Time:      time.Now().Unix(),
+		Resources: a.Control.QueryStats(),
+	}
+	if a.Control.Rules != nil {
+		hb.RuleVersion = a.Control.Rules.CurrentVersion()
+	}
+
+	body, err := json.Marshal(hb)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("governance: heartbeat rejected with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// 定时上报心跳，ctx结束时返回
+func (a *HeartbeatAgent) Run(ctx context.Context) {
+	interval := a.Config.interval()
+	timer := time.NewTimer(jitter(interval, a.Config.Jitter))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			if err := a.Report(ctx); err != nil && ctx.Err() == nil {
+				a.reportFailed(err)
+			}
+			timer.Reset(jitter(interval, a.Config.Jitter))
+		}
+	}
+}
+
+// 记录上报失败
+func (a *HeartbeatAgent) reportFailed(err error) {
+	atomic.AddInt64(&a.failures, 1)
+	logDecision(context.Background(), LogReport, slog.LevelWarn, "heartbeat report failed", a.Config.Endpoint, "heartbeat", "retry later",
+		slog.String("error", err.Error()))
+	if a.OnError != nil {
+		a.OnError(err)
+	}
+}
+
+// Run中累计上报失败的次数
+func (a *HeartbeatAgent) Failures() int64 {
+	return atomic.LoadInt64(&a.failures)
+}
@@ -0,0 +1,202 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 没有可用实例时返回的错误
+var ErrNoInstance = errors.New("governance: no available instance")
+
+// 实例选择器，为服务service选择不在exclude中的实例
+type InstancePicker interface {
+	PickInstance(service string, exclude map[string]bool) (string, bool)
+}
+
+// 基于静态实例列表和实例熔断状态的实例选择器，实例的rpc资源名为ResourceKey{Service, Instance}编码
+type StaticInstances struct {
+	Breaker   *Breaker
+	SlowStart time.Duration // 新增实例的预热时长，预热期间权重从0线性增长到1，为0时不预热
+
+	sync.RWMutex
+	instances map[string][]string
+	addedAt   map[string]time.Time                 // 实例加入的时间，key为ResourceKey{Service, Instance}编码
+	watchers  map[string]map[*instanceWatcher]bool // 服务实例列表变化的监听
+}
+
+// 实例列表变化的监听
+type instanceWatcher struct {
+	fn func(instances []string)
+}
+
+// 预热期间实例的最小权重
+const minSlowStartWeight = 0.01
+
+// 创建静态实例选择器
+func NewStaticInstances(breaker *Breaker) *StaticInstances {
+	return &StaticInstances{
+		Breaker:   breaker,
+		instances: make(map[string][]string),
+		addedAt:   make(map[string]time.Time),
+		watchers:  make(map[string]map[*instanceWatcher]bool),
+	}
+}
+
+// 设置服务service的实例列表，并通知Watch注册的监听
+func (s *StaticInstances) SetInstances(service string, instances []string) {
+	s.Lock()
+	watchers := make([]*instanceWatcher, 0, len(s.watchers[service]))
+	for w := range s.watchers[service] {
+		watchers = append(watchers, w)
+	}
+	s.setInstances(service, instances)
+	s.Unlock()
+
+	for _, w := range watchers {
+		w.fn(instances)
+	}
+}
+
+// 设置服务service的实例列表，调用方需持有写锁
+func (s *StaticInstances) setInstances(service string, instances []string) {
+	now := time.Now()
+	current := make(map[string]bool, len(instances))
+	for _, inst := range instances {
+		key := ResourceKey{Service: service, Instance: inst}.Encode()
+		current[key] = true
+		if _, ok := s.addedAt[key]; !ok {
+			s.addedAt[key] = now
+		}
+	}
+	for _, inst := range s.instances[service] {
+		key := ResourceKey{Service: service, Instance: inst}.Encode()
+		if !current[key] {
+			delete(s.addedAt, key)
+		}
+	}
+	s.instances[service] = instances
+}
+
+// 监听服务service实例列表的变化，注册后立即以当前实例列表调用一次fn，返回取消监听的函数
+func (s *StaticInstances) Watch(service string, fn func(instances []string)) (cancel func()) {
+	w := &instanceWatcher{fn: fn}
+	s.Lock()
+	if s.watchers[service] == nil {
+		s.watchers[service] = make(map[*instanceWatcher]bool)
+	}
+	s.watchers[service][w] = true
+	instances := s.instances[service]
+	s.Unlock()
+
+	fn(instances)
+
+	return func() {
+		s.Lock()
+		defer s.Unlock()
+
+		delete(s.watchers[service], w)
+		if len(s.watchers[service]) == 0 {
+			delete(s.watchers, service)
+		}
+	}
+}
+
+// 从注册中心reg同步服务service的实例列表，使按熔断状态选择实例、预热等功能可用于任意注册中心，返回停止同步的函数
+func (s *StaticInstances) Sync(reg Registry, service string) (cancel func()) {
+	return reg.Watch(service, func(instances []string) {
+		s.SetInstances(service, instances)
+	})
+}
+
+// 实例当前的预热权重
+func (s *StaticInstances) weight(service, inst string, now time.Time) float64 {
+	if s.SlowStart <= 0 {
+		return 1
+	}
+
+	s.RLock()
+	addedAt, ok := s.addedAt[ResourceKey{Service: service, Instance: inst}.Encode()]
+	s.RUnlock()
+	if !ok {
+		return 1
+	}
+
+	w := float64(now.Sub(addedAt)) / float64(s.SlowStart)
+	if w > 1 {
+		return 1
+	}
+	if w < minSlowStartWeight {
+		return minSlowStartWeight
+	}
+
+	return w
+}
+
+// 获取服务service的实例列表
+func (s *StaticInstances) Instances(service string) []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.instances[service]
+}
+
+// 从未熔断且不在exclude中的实例中按预热权重随机选择一个
+func (s *StaticInstances) PickInstance(service string, exclude map[string]bool) (string, bool) {
+	var candidates []string
+	for _, inst := range s.Instances(service) {
+		if exclude[inst] {
+			continue
+		}
+		if s.Breaker != nil && !s.Breaker.Available(ResourceKey{Service: service, Instance: inst}.Encode()) {
+			continue
+		}
+		candidates = append(candidates, inst)
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	now := time.Now()
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, inst := range candidates {
+		weights[i] = s.weight(service, inst, now)
+		total += weights[i]
+	}
+
+	n := rand.Float64() * total
+	for i, w := range weights {
+		if n < w {
+			return candidates[i], true
+		}
+		n -= w
+	}
+
+	return candidates[len(candidates)-1], true
+}
+
+// 调用服务service，每次尝试选择不同的实例，排除之前尝试失败的实例
+func (rt *Retry) DoRouted(ctx context.Context, service string, picker InstancePicker, fn func(ctx context.Context, instance string) error) error {
+	classify := rt.Classifier
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+
+	failed := make(map[string]bool)
+	return rt.Do(ctx, func(ctx context.Context) error {
+		inst, ok := picker.PickInstance(service, failed)
+		if !ok {
+			return ErrNoInstance
+		}
+
+		err := fn(ctx, inst)
+		if classify(err) == OutcomeFailure {
+			failed[inst] = true
+		}
+
+		return err
+	})
+}
@@ -0,0 +1,90 @@
+package governance
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// rpc资源r正在执行的调用数计数器
+func (breaker *Breaker) inflightOf(r string) *int64 {
+	if v, ok := breaker.inflight.Load(r); ok {
+		return v.(*int64)
+	}
+	v, _ := breaker.inflight.LoadOrStore(r, new(int64))
+
+	return v.(*int64)
+}
+
+// 各rpc资源正在执行的调用数，持续增长说明下游调用阻塞导致goroutine堆积
+func (breaker *Breaker) InFlight() map[string]int64 {
+	inflight := make(map[string]int64)
+	breaker.inflight.Range(func(k, v interface{}) bool {
+		inflight[k.(string)] = atomic.LoadInt64(v.(*int64))
+		return true
+	})
+
+	return inflight
+}
+
+// 按依赖统计打开连接数的拨号器，可用于http.Transport.DialContext等
+type TrackingDialer struct {
+	Dialer *net.Dialer // 实际建立连接的拨号器，为空时使用零值net.Dialer
+	// 连接所属的依赖，为空时按地址统计
+	Resource func(network, addr string) string
+
+	conns sync.Map // 依赖当前打开的连接数，值为*int64
+}
+
+func (d *TrackingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := addr
+	if d.Resource != nil {
+		r = d.Resource(network, addr)
+	}
+	v, _ := d.conns.LoadOrStore(r, new(int64))
+	n := v.(*int64)
+	atomic.AddInt64(n, 1)
+
+	return &trackedConn{Conn: conn, n: n}, nil
+}
+
+// 各依赖当前打开的连接数
+func (d *TrackingDialer) OpenConns() map[string]int64 {
+	conns := make(map[string]int64)
+	d.conns.Range(func(k, v interface{}) bool {
+		conns[k.(string)] = atomic.LoadInt64(v.(*int64))
+		return true
+	})
+
+	return conns
+}
+
+// 关闭时减少所属依赖连接数的连接
+type trackedConn struct {
+	net.Conn
+	n    *int64
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { atomic.AddInt64(c.n, -1) })
+	return c.Conn.Close()
+}
+
+// 以name发布拨号器按依赖统计的打开连接数到expvar
+func PublishDialerExpvar(name string, dialer *TrackingDialer) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return dialer.OpenConns()
+	}))
+}
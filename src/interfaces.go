@@ -0,0 +1,49 @@
+package governance
+
+import (
+	"context"
+)
+
+// 熔断器接口，Breaker为默认实现
+type CircuitBreaker interface {
+	Allow(r string) bool
+	Execute(ctx context.Context, r string, fn func(ctx context.Context) error) error
+}
+
+// 限流器接口，Limiter和SpikeArrester为默认实现
+type RateLimiter interface {
+	Allow(key string) bool
+}
+
+// 阻塞等待的限流器接口，Limiter为默认实现
+type WaitLimiter interface {
+	RateLimiter
+	Wait(ctx context.Context, key string) error
+}
+
+// 重试接口，Retry为默认实现
+type Retryer interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// 服务注册中心接口，etcd、consul等注册中心实现此接口后可通过StaticInstances.Sync作为实例来源，StaticInstances为默认实现
+type Registry interface {
+	// 获取服务service当前的实例列表
+	Instances(service string) []string
+	// 监听服务service实例列表的变化，注册后立即以当前实例列表调用一次fn，返回取消监听的函数
+	Watch(service string, fn func(instances []string)) (cancel func())
+}
+
+// 负载均衡接口，返回服务service本次调用的目标，Router为默认实现
+type Balancer interface {
+	Pick(service string) (string, bool)
+}
+
+var (
+	_ CircuitBreaker = (*Breaker)(nil)
+	_ RateLimiter    = (*SpikeArrester)(nil)
+	_ WaitLimiter    = (*Limiter)(nil)
+	_ Retryer        = (*Retry)(nil)
+	_ Balancer       = (*Router)(nil)
+	_ Registry       = (*StaticInstances)(nil)
+)
@@ -0,0 +1,163 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// 隔离资源已满时返回的错误
+	ErrIsolationRejected = errors.New("governance: isolation capacity exceeded")
+	// 线程池隔离下调用超时返回的错误
+	ErrIsolationTimeout = errors.New("governance: isolated call timed out")
+)
+
+// 隔离策略
+const (
+	SemaphoreIsolation = "semaphore" // 信号量隔离，在调用方goroutine中执行，限制并发数
+	PoolIsolation      = "pool"      // 线程池隔离，在独立的工作goroutine中执行，带队列和超时
+)
+
+// 隔离配置
+type IsolationConfig struct {
+	Mode          string `toml:"mode"`           // 隔离策略，semaphore或pool
+	MaxConcurrent int    `toml:"max_concurrent"` // 最大并发数，线程池隔离下为工作goroutine数
+	QueueSize     int    `toml:"queue_size"`     // 线程池隔离的队列长度
+	Timeout       int64  `toml:"timeout"`        // 线程池隔离的调用超时时间，单位毫秒，0表示不超时
+}
+
+// 隔离舱
+type bulkhead interface {
+	execute(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// 信号量隔离
+type semaphoreBulkhead struct {
+	sem chan struct{}
+}
+
+func (b *semaphoreBulkhead) execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	select {
+	case b.sem <- struct{}{}:
+	default:
+		return ErrIsolationRejected
+	}
+	defer func() { <-b.sem }()
+
+	return fn(ctx)
+}
+
+// 线程池隔离的任务
+type poolTask struct {
+	ctx    context.Context
+	fn     func(ctx context.Context) error
+	result chan error
+}
+
+// 线程池隔离
+type poolBulkhead struct {
+	tasks   chan *poolTask
+	timeout time.Duration
+}
+
+func newPoolBulkhead(config *IsolationConfig) *poolBulkhead {
+	b := &poolBulkhead{
+		tasks:   make(chan *poolTask, config.QueueSize),
+		timeout: time.Duration(config.Timeout) * time.Millisecond,
+	}
+	for i := 0; i < config.MaxConcurrent; i++ {
+		go b.work()
+	}
+
+	return b
+}
+
+// 工作goroutine，依次执行队列中的任务
+func (b *poolBulkhead) work() {
+	for task := range b.tasks {
+		task.result <- task.fn(task.ctx)
+	}
+}
+
+func (b *poolBulkhead) execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	task := &poolTask{ctx: ctx, fn: fn, result: make(chan error, 1)}
+	select {
+	case b.tasks <- task:
+	default:
+		return ErrIsolationRejected
+	}
+
+	var timeout <-chan time.Time
+	if b.timeout > 0 {
+		timer := time.NewTimer(b.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case err := <-task.result:
+		return err
+	case <-timeout:
+		return ErrIsolationTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 按rpc资源隔离调用
+type Isolator struct {
+	Default *IsolationConfig // 默认隔离配置
+
+	sync.Mutex
+	configs   map[string]*IsolationConfig
+	bulkheads map[string]bulkhead
+}
+
+// 创建隔离器
+func NewIsolator(def *IsolationConfig) *Isolator {
+	return &Isolator{
+		Default:   def,
+		configs:   make(map[string]*IsolationConfig),
+		bulkheads: make(map[string]bulkhead),
+	}
+}
+
+// 设置rpc资源r的隔离配置，需在首次调用前设置
+func (iso *Isolator) SetConfig(r string, config *IsolationConfig) {
+	iso.Lock()
+	defer iso.Unlock()
+
+	iso.configs[r] = config
+}
+
+// 在隔离舱中调用rpc资源r
+func (iso *Isolator) Execute(ctx context.Context, r string, fn func(ctx context.Context) error) error {
+	return iso.bulkhead(r).execute(ctx, fn)
+}
+
+// 获取rpc资源r的隔离舱，不存在时按配置创建
+func (iso *Isolator) bulkhead(r string) bulkhead {
+	iso.Lock()
+	defer iso.Unlock()
+
+	if b, ok := iso.bulkheads[r]; ok {
+		return b
+	}
+
+	config, ok := iso.configs[r]
+	if !ok {
+		config = iso.Default
+	}
+
+	var b bulkhead
+	if config.Mode == PoolIsolation {
+		b = newPoolBulkhead(config)
+	} else {
+		b = &semaphoreBulkhead{sem: make(chan struct{}, config.MaxConcurrent)}
+	}
+	iso.bulkheads[r] = b
+
+	return b
+}
@@ -0,0 +1,18 @@
+package governance
+
+import (
+	"math/rand"
+	"time"
+)
+
+// 为间隔d加上随机抖动，结果在[d*(1-ratio), d*(1+ratio)]内均匀分布
+func jitter(d time.Duration, ratio float64) time.Duration {
+	if ratio <= 0 {
+		return d
+	}
+	if ratio > 1 {
+		ratio = 1
+	}
+
+	return time.Duration(float64(d) * (1 + ratio*(2*rand.Float64()-1)))
+}
@@ -0,0 +1,163 @@
+package governance
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// 按键隔离的键数达到上限且没有可淘汰的空闲键时的处理策略
+const (
+	OverflowReject = "reject" // 拒绝新键的调用
+	OverflowShared = "shared" // 新键共用一个溢出隔离舱，按MaxPerKey限制并发
+)
+
+// 按键隔离配置
+type KeyedIsolationConfig struct {
+	MaxPerKey   int    `toml:"max_per_key"`  // 每个键的最大并发数
+	MaxKeys     int    `toml:"max_keys"`     // 最多跟踪的键数，超过时淘汰最久未使用的空闲键，0表示不限制
+	Overflow    string `toml:"overflow"`     // 键数达到上限时的处理策略，reject或shared，默认reject
+	WaitTimeout int64  `toml:"wait_timeout"` // 键的并发数已满时等待的时间，单位毫秒，0表示立即拒绝
+}
+
+// 单个键的并发槽位
+type keySlot struct {
+	key      string
+	sem      chan struct{}
+	inflight int // 正在执行和等待的调用数，大于0时不会被淘汰
+}
+
+// 按用户或租户隔离的并发限制，防止单个租户占满所有工作goroutine
+type KeyedIsolator struct {
+	Config *KeyedIsolationConfig
+
+	sync.Mutex
+	slots    map[string]*list.Element // 值为*keySlot，按最近使用排序
+	lru      *list.List
+	overflow *keySlot
+	rejected int64
+}
+
+// 创建按键隔离器
+func NewKeyedIsolator(config *KeyedIsolationConfig) *KeyedIsolator {
+	return &KeyedIsolator{
+		Config:   config,
+		slots:    make(map[string]*list.Element),
+		lru:      list.New(),
+		overflow: &keySlot{sem: make(chan struct{}, config.MaxPerKey)},
+	}
+}
+
+// 获取键key的槽位并增加引用，键数已满且无法淘汰时按溢出策略处理
+func (iso *KeyedIsolator) acquire(key string) (*keySlot, bool) {
+	iso.Lock()
+	defer iso.Unlock()
+
+	if e, ok := iso.slots[key]; ok {
+		iso.lru.MoveToFront(e)
+		slot := e.Value.(*keySlot)
+		slot.inflight++
+		return slot, true
+	}
+
+	if iso.Config.MaxKeys > 0 && iso.lru.Len() >= iso.Config.MaxKeys && !iso.evict() {
+		if iso.Config.Overflow != OverflowShared {
+			return nil, false
+		}
+		iso.overflow.inflight++
+		return iso.overflow, true
+	}
+
+	slot := &keySlot{key: key, sem: make(chan struct{}, iso.Config.MaxPerKey), inflight: 1}
+	iso.slots[key] = iso.lru.PushFront(slot)
+
+	return slot, true
+}
+
+// 淘汰最久未使用的空闲键，调用方需持有锁
+func (iso *KeyedIsolator) evict() bool {
+	for e := iso.lru.Back(); e != nil; e = e.Prev() {
+		slot := e.Value.(*keySlot)
+		if slot.inflight == 0 {
+			iso.lru.Remove(e)
+			delete(iso.slots, slot.key)
+			return true
+		}
+	}
+
+	return false
+}
+
+// 减少槽位的引用
+func (iso *KeyedIsolator) release(slot *keySlot) {
+	iso.Lock()
+	slot.inflight--
+	iso.Unlock()
+}
+
+// 以键key的并发限制调用fn，key通常为用户或租户标识
+func (iso *KeyedIsolator) Execute(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	slot, ok := iso.acquire(key)
+	if !ok {
+		iso.reject()
+		return ErrIsolationRejected
+	}
+	defer iso.release(slot)
+
+	select {
+	case slot.sem <- struct{}{}:
+	default:
+		if iso.Config.WaitTimeout <= 0 {
+			iso.reject()
+			return ErrIsolationRejected
+		}
+		timer := time.NewTimer(time.Duration(iso.Config.WaitTimeout) * time.Millisecond)
+		defer timer.Stop()
+		select {
+		case slot.sem <- struct{}{}:
+		case <-timer.C:
+			iso.reject()
+			return ErrIsolationRejected
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer func() { <-slot.sem }()
+
+	return fn(ctx)
+}
+
+func (iso *KeyedIsolator) reject() {
+	iso.Lock()
+	iso.rejected++
+	iso.Unlock()
+}
+
+// 键key正在执行的调用数
+func (iso *KeyedIsolator) InFlight(key string) int {
+	iso.Lock()
+	defer iso.Unlock()
+
+	if e, ok := iso.slots[key]; ok {
+		return len(e.Value.(*keySlot).sem)
+	}
+
+	return 0
+}
+
+// 当前跟踪的键数
+func (iso *KeyedIsolator) Keys() int {
+	iso.Lock()
+	defer iso.Unlock()
+
+	return iso.lru.Len()
+}
+
+// 累计被拒绝的调用数
+func (iso *KeyedIsolator) Rejected() int64 {
+	iso.Lock()
+	defer iso.Unlock()
+
+	return iso.rejected
+}
@@ -0,0 +1,59 @@
+package governance
+
+// 共享限流的资源组
+type LimitGroup struct {
+	Name      string        `toml:"name"`      // 资源组名
+	Resources []string      `toml:"resources"` // 组内的rpc资源
+	Limiter   LimiterConfig `toml:"limiter"`   // 组内共享的限流配置
+}
+
+// 分层限流器，请求需同时通过rpc资源自身的限流和所属资源组的共享限流
+type HierarchicalLimiter struct {
+	Resource *Limiter            // rpc资源级限流，以rpc资源名为调用方，为空时不限流
+	groups   map[string]*Limiter // 资源组共享的限流器
+	members  map[string][]string // rpc资源所属的资源组
+}
+
+// 创建分层限流器，resource为空时只按资源组限流
+func NewHierarchicalLimiter(resource *LimiterConfig, groups []LimitGroup) *HierarchicalLimiter {
+	h := &HierarchicalLimiter{
+		groups:  make(map[string]*Limiter, len(groups)),
+		members: make(map[string][]string),
+	}
+	if resource != nil {
+		h.Resource = NewLimiter(resource)
+	}
+	for i := range groups {
+		g := &groups[i]
+		h.groups[g.Name] = NewLimiter(&g.Limiter)
+		for _, r := range g.Resources {
+			h.members[r] = append(h.members[r], g.Name)
+		}
+	}
+
+	return h
+}
+
+// 判断rpc资源r的请求是否允许执行，任一层拒绝时归还已获取的令牌
+func (h *HierarchicalLimiter) Allow(r string) bool {
+	if h.Resource != nil && !h.Resource.Allow(r) {
+		return false
+	}
+
+	groups := h.members[r]
+	for i, name := range groups {
+		if !h.groups[name].Allow(name) {
+			for _, acquired := range groups[:i] {
+				h.groups[acquired].cancel(acquired, 1)
+			}
+			if h.Resource != nil {
+				h.Resource.cancel(r, 1)
+			}
+			return false
+		}
+	}
+
+	return true
+}
+
+var _ RateLimiter = (*HierarchicalLimiter)(nil)
@@ -0,0 +1,299 @@
+package governance
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+type costKey struct{}
+
+// 声明请求的代价，Governance.Execute按代价限流
+func WithCost(ctx context.Context, cost float64) context.Context {
+	return context.WithValue(ctx, costKey{}, cost)
+}
+
+// 获取请求声明的代价，未声明时为1
+func CostFrom(ctx context.Context) float64 {
+	if cost, ok := ctx.Value(costKey{}).(float64); ok {
+		return cost
+	}
+
+	return 1
+}
+
+// 限流器配置
+type LimiterConfig struct {
+	QPS   float64 `toml:"qps"`   // 每秒允许的请求数，按代价限流时为每秒允许的总代价
+	Burst int     `toml:"burst"` // 允许的突发请求数，按代价限流时为允许的突发总代价
+	Delay bool    `toml:"delay"` // 超限请求是否延迟执行而不是直接拒绝
+	// 每个调用方累计延迟的上限，单位毫秒，超过此值的请求仍被拒绝，延迟模式下为0时使用默认值1秒
+	MaxDelay int64 `toml:"max_delay"`
+	// 是否为试运行模式，试运行模式下只记录会被限流的请求而不拒绝
+	DryRun bool `toml:"dry_run"`
+}
+
+// 延迟模式下每个调用方累计延迟上限的默认值
+const defaultMaxDelay = time.Second
+
+// 每个调用方累计延迟的上限，未设置时使用默认值，避免延迟模式退化为直接拒绝
+func (config *LimiterConfig) maxDelay() time.Duration {
+	if config.MaxDelay <= 0 {
+		return defaultMaxDelay
+	}
+
+	return time.Duration(config.MaxDelay) * time.Millisecond
+}
+
+// 突发数，未设置时为max(1, ceil(qps))，允许一秒内的请求同时到达，突发数为0的令牌桶拒绝所有请求
+func defaultBurst(qps float64, burst int) int {
+	if burst > 0 {
+		return burst
+	}
+
+	return int(math.Max(1, math.Ceil(qps)))
+}
+
+// 调用方的令牌桶
+type bucket struct {
+	tokens float64   // 当前令牌数，延迟模式下可为负数，表示调用方累计的延迟
+	last   time.Time // 上次更新令牌的时间
+	scale  float64   // 补充令牌速率的缩放比例，0表示不缩放
+	paused time.Time // 在此时间之前暂停放行
+}
+
+// 按调用方限流的令牌桶限流器
+type Limiter struct {
+	Config *LimiterConfig
+	// 延迟模式下请求获得许可时调用，wait为等待令牌的时间，可用于在链路追踪中标注限流整形造成的延迟
+	OnWait func(ctx context.Context, key string, wait time.Duration)
+	// 调用方key的请求被限流时调用
+	OnReject func(key string)
+	sync.Mutex
+	buckets map[string]*bucket
+
+	dryRunRejects int64         // 试运行模式下会被限流的次数
+	waits         waitHistogram // 延迟模式下等待令牌的时间分布
+}
+
+// 创建限流器
+func NewLimiter(config *LimiterConfig) *Limiter {
+	return &Limiter{
+		Config:  config,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// 替换限流器配置
+func (l *Limiter) SetConfig(config *LimiterConfig) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.Config = config
+}
+
+// 获取限流器配置
+func (l *Limiter) GetConfig() *LimiterConfig {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.Config
+}
+
+// 判断调用方key的请求是否允许立即执行
+func (l *Limiter) Allow(key string) bool {
+	return l.AllowN(key, 1)
+}
+
+// 判断调用方key代价为cost的请求是否允许立即执行，代价可以是字节数、行数或计算单元
+func (l *Limiter) AllowN(key string, cost float64) bool {
+	if l.allowAt(key, cost, time.Now()) {
+		return true
+	}
+	l.reject(context.Background(), key, cost)
+
+	return false
+}
+
+// 按当前时间now判断调用方key代价为cost的请求是否允许执行
+func (l *Limiter) allowAt(key string, cost float64, now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	b := l.refill(key, now)
+	if b.tokens < cost || now.Before(b.paused) {
+		if l.Config.DryRun {
+			l.dryRunRejects++
+			return true
+		}
+		return false
+	}
+	b.tokens -= cost
+
+	return true
+}
+
+// 试运行模式下会被限流的次数
+func (l *Limiter) DryRunRejects() int64 {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.dryRunRejects
+}
+
+// 获取调用方key的执行许可，延迟模式下等待到可以执行，累计延迟超过上限或ctx结束时返回错误
+func (l *Limiter) Wait(ctx context.Context, key string) error {
+	return l.WaitN(ctx, key, 1)
+}
+
+// 获取调用方key代价为cost的请求的执行许可，行为同Wait
+func (l *Limiter) WaitN(ctx context.Context, key string, cost float64) error {
+	if config := l.GetConfig(); !config.Delay || config.DryRun {
+		if l.AllowN(key, cost) {
+			return nil
+		}
+		return ErrRateLimited
+	}
+
+	delay, ok := l.reserve(key, cost)
+	if !ok {
+		l.reject(ctx, key, cost)
+		return ErrRateLimited
+	}
+	if delay <= 0 {
+		l.observeWait(ctx, key, 0)
+		return nil
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		l.observeWait(ctx, key, time.Since(start))
+		return nil
+	case <-ctx.Done():
+		l.cancel(key, cost)
+		return ctx.Err()
+	}
+}
+
+// 记录调用方key代价为cost的请求被限流
+func (l *Limiter) reject(ctx context.Context, key string, cost float64) {
+	logDecision(ctx, LogLimiter, slog.LevelInfo, "rate limited", key, "limited", "reject",
+		slog.Float64("cost", cost))
+	if l.OnReject != nil {
+		l.OnReject(key)
+	}
+}
+
+// 记录调用方key的请求等待令牌的时间
+func (l *Limiter) observeWait(ctx context.Context, key string, wait time.Duration) {
+	l.waits.observe(wait)
+	if wait > 0 {
+		logDecision(ctx, LogLimiter, slog.LevelDebug, "rate limit delayed", key, "limited", "delay",
+			slog.Duration("wait", wait))
+	}
+	if l.OnWait != nil {
+		l.OnWait(ctx, key, wait)
+	}
+}
+
+// 预占调用方key的cost个令牌，返回需要延迟的时间
+func (l *Limiter) reserve(key string, cost float64) (time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	b := l.refill(key, now)
+	tokens := b.tokens - cost
+	var delay time.Duration
+	if tokens < 0 {
+		qps := l.Config.QPS * b.rate()
+		if qps <= 0 {
+			return 0, false
+		}
+		delay = time.Duration(-tokens / qps * float64(time.Second))
+	}
+	if pause := b.paused.Sub(now); pause > delay {
+		delay = pause
+	}
+	if delay > l.Config.maxDelay() {
+		return 0, false
+	}
+	b.tokens = tokens
+
+	return delay, true
+}
+
+// 归还调用方key预占的cost个令牌
+func (l *Limiter) cancel(key string, cost float64) {
+	l.Lock()
+	defer l.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens += cost
+	}
+}
+
+// 按流逝的时间补充调用方key的令牌，调用方需持有锁
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Config.Burst), last: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.Config.QPS * b.rate()
+	if b.tokens > float64(l.Config.Burst) {
+		b.tokens = float64(l.Config.Burst)
+	}
+	b.last = now
+
+	return b
+}
+
+// 删除已补满且未被Throttle调整的令牌桶，这些令牌桶与新建的令牌桶等价，返回删除的个数
+// 调用方key数量不受控时（如按来源IP限流）需定期调用，避免令牌桶无限增长
+func (l *Limiter) Prune(now time.Time) int {
+	l.Lock()
+	defer l.Unlock()
+
+	n := 0
+	for key, b := range l.buckets {
+		if b.scale > 0 || now.Before(b.paused) {
+			continue
+		}
+		if b.tokens+now.Sub(b.last).Seconds()*l.Config.QPS >= float64(l.Config.Burst) {
+			delete(l.buckets, key)
+			n++
+		}
+	}
+
+	return n
+}
+
+// 补充令牌速率的缩放比例
+func (b *bucket) rate() float64 {
+	if b.scale <= 0 {
+		return 1
+	}
+
+	return b.scale
+}
+
+// 动态调整调用方key的限流，补充令牌的速率按scale缩放，until之前暂停放行，scale为1时恢复配置的速率
+func (l *Limiter) Throttle(key string, scale float64, until time.Time) {
+	l.Lock()
+	defer l.Unlock()
+
+	b := l.refill(key, time.Now())
+	b.scale = scale
+	if until.After(b.paused) {
+		b.paused = until
+	}
+}
@@ -0,0 +1,55 @@
+package governance
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLimiterPrune(t *testing.T) {
+	l := NewLimiter(&LimiterConfig{QPS: 10, Burst: 5})
+	now := time.Now()
+	l.allowAt("idle", 5, now)
+	l.allowAt("busy", 5, now)
+	l.Throttle("throttled", 0.5, time.Time{})
+
+	// 0.5秒后idle已补满，busy在此之前刚被使用
+	l.allowAt("busy", 1, now.Add(400*time.Millisecond))
+	if n := l.Prune(now.Add(500 * time.Millisecond)); n != 1 {
+		t.Fatalf("Prune removed %d buckets, want 1", n)
+	}
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket not pruned")
+	}
+	if _, ok := l.buckets["busy"]; !ok {
+		t.Error("busy bucket pruned before refilled")
+	}
+	if _, ok := l.buckets["throttled"]; !ok {
+		t.Error("throttled bucket pruned")
+	}
+}
+
+// 未设置每个来源IP的突发数时按速率取默认值，而不是拒绝所有连接
+func TestListenerDefaultBurst(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	gl := WrapListener(l, &ListenerConfig{PerIPRate: 0.5})
+	defer gl.Close()
+
+	go func() {
+		if conn, err := net.Dial("tcp", l.Addr().String()); err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	conn, err := gl.Accept()
+	if err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	conn.Close()
+	if gl.Rejected() != 0 {
+		t.Fatalf("rejected %d connections, want 0", gl.Rejected())
+	}
+}
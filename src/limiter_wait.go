@@ -0,0 +1,82 @@
+package governance
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 延迟模式下等待令牌的时间分布，用于区分限流整形造成的延迟和上游的耗时
+type WaitStats struct {
+	Count   int64         `json:"count"`   // 获得许可的请求数，包括无需等待的请求
+	Delayed int64         `json:"delayed"` // 需要等待的请求数
+	Mean    time.Duration `json:"mean"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+	P99     time.Duration `json:"p99"`
+	Max     time.Duration `json:"max"`
+}
+
+// 等待时间的累计分布，分桶方式与滑动窗口统计的耗时分布相同
+type waitHistogram struct {
+	sync.Mutex
+	count   int64
+	delayed int64
+	sum     time.Duration
+	max     time.Duration
+	buckets [latencyBuckets]int64
+}
+
+func (h *waitHistogram) observe(d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+
+	h.count++
+	if d > 0 {
+		h.delayed++
+	}
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+	h.buckets[latencyBucket(d)]++
+}
+
+// 分布的q分位数，无需等待的请求计为0
+func (h *waitHistogram) quantile(q float64) time.Duration {
+	rank := int64(math.Ceil(q * float64(h.count)))
+	if rank <= h.count-h.delayed {
+		return 0
+	}
+
+	var seen int64
+	for i, c := range h.buckets {
+		seen += c
+		if seen >= rank && c > 0 {
+			return latencyUpper(i)
+		}
+	}
+
+	return h.max
+}
+
+func (h *waitHistogram) stats() WaitStats {
+	h.Lock()
+	defer h.Unlock()
+
+	s := WaitStats{Count: h.count, Delayed: h.delayed, Max: h.max}
+	if h.count == 0 {
+		return s
+	}
+	s.Mean = h.sum / time.Duration(h.count)
+	s.P50 = h.quantile(0.5)
+	s.P90 = h.quantile(0.9)
+	s.P99 = h.quantile(0.99)
+
+	return s
+}
+
+// 延迟模式下请求等待令牌的时间分布
+func (l *Limiter) WaitStats() WaitStats {
+	return l.waits.stats()
+}
@@ -0,0 +1,118 @@
+package governance
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 连接级治理配置
+type ListenerConfig struct {
+	MaxConns   int     `toml:"max_conns"`    // 最大并发连接数，0表示不限制
+	PerIPRate  float64 `toml:"per_ip_rate"`  // 每个来源IP每秒允许新建的连接数，0表示不限制
+	PerIPBurst int     `toml:"per_ip_burst"` // 每个来源IP允许突发新建的连接数，为0时使用max(1, ceil(PerIPRate))
+}
+
+// 带连接治理的监听器，超过限制的连接在accept后立即关闭，避免内核accept队列堆积
+type GovernedListener struct {
+	net.Listener
+	Config *ListenerConfig
+
+	sem       chan struct{}
+	limiter   *Limiter
+	rejected  int64 // 被拒绝的连接数
+	lastPrune int64 // 上次清理来源IP令牌桶的时间，单位纳秒
+}
+
+// 清理空闲来源IP令牌桶的间隔
+const listenerPruneInterval = time.Minute
+
+// 包装监听器
+func WrapListener(l net.Listener, config *ListenerConfig) *GovernedListener {
+	gl := &GovernedListener{
+		Listener: l,
+		Config:   config,
+	}
+	if config.MaxConns > 0 {
+		gl.sem = make(chan struct{}, config.MaxConns)
+	}
+	if config.PerIPRate > 0 {
+		gl.limiter = NewLimiter(&LimiterConfig{QPS: config.PerIPRate, Burst: defaultBurst(config.PerIPRate, config.PerIPBurst)})
+	}
+
+	return gl
+}
+
+func (gl *GovernedListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := gl.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if gl.limiter != nil {
+			gl.prune()
+			if !gl.limiter.Allow(remoteIP(conn)) {
+				gl.reject(conn)
+				continue
+			}
+		}
+		if gl.sem == nil {
+			return conn, nil
+		}
+
+		select {
+		case gl.sem <- struct{}{}:
+			return &governedConn{Conn: conn, release: func() { <-gl.sem }}, nil
+		default:
+			gl.reject(conn)
+		}
+	}
+}
+
+// 每隔listenerPruneInterval删除已补满的来源IP令牌桶，避免大量不同来源IP使令牌桶无限增长
+func (gl *GovernedListener) prune() {
+	now := time.Now()
+	last := atomic.LoadInt64(&gl.lastPrune)
+	if now.UnixNano()-last < int64(listenerPruneInterval) || !atomic.CompareAndSwapInt64(&gl.lastPrune, last, now.UnixNano()) {
+		return
+	}
+
+	gl.limiter.Prune(now)
+}
+
+// 拒绝连接
+func (gl *GovernedListener) reject(conn net.Conn) {
+	atomic.AddInt64(&gl.rejected, 1)
+	conn.Close()
+}
+
+// 被拒绝的连接数
+func (gl *GovernedListener) Rejected() int64 {
+	return atomic.LoadInt64(&gl.rejected)
+}
+
+// 获取连接的来源IP
+func remoteIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn.RemoteAddr().String()
+	}
+
+	return host
+}
+
+// 关闭时释放并发名额的连接
+type governedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *governedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+
+	return err
+}
@@ -0,0 +1,84 @@
+package governance
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// 日志模块，可按模块设置日志级别
+const (
+	LogBreaker   = "breaker"
+	LogLimiter   = "limiter"
+	LogAdmission = "admission"
+	LogWatchdog  = "watchdog"
+	LogRules     = "rules"
+	LogReport    = "report" // 心跳、告警webhook等对外上报
+)
+
+// 治理日志的输出和各模块的日志级别
+var logging = struct {
+	sync.RWMutex
+	logger *slog.Logger
+	levels map[string]slog.Level
+}{
+	levels: map[string]slog.Level{
+		LogBreaker: slog.LevelInfo,
+		LogLimiter: slog.LevelWarn,
+	},
+}
+
+// 设置治理日志的输出，为空时使用slog.Default()
+func SetLogger(l *slog.Logger) {
+	logging.Lock()
+	defer logging.Unlock()
+
+	logging.logger = l
+}
+
+// 设置模块module的日志级别，未设置的模块为Info
+func SetLogLevel(module string, level slog.Level) {
+	logging.Lock()
+	defer logging.Unlock()
+
+	logging.levels[module] = level
+}
+
+// 模块module在level级别的日志是否输出，不输出时返回空
+func moduleLogger(ctx context.Context, module string, level slog.Level) *slog.Logger {
+	logging.RLock()
+	l := logging.logger
+	min, ok := logging.levels[module]
+	logging.RUnlock()
+
+	if !ok {
+		min = slog.LevelInfo
+	}
+	if level < min {
+		return nil
+	}
+	if l == nil {
+		l = slog.Default()
+	}
+	if !l.Enabled(ctx, level) {
+		return nil
+	}
+
+	return l
+}
+
+// 输出治理日志，每条日志都带有模块、rpc资源、状态和决策字段
+func logDecision(ctx context.Context, module string, level slog.Level, msg, resource, state, decision string, attrs ...slog.Attr) {
+	l := moduleLogger(ctx, module, level)
+	if l == nil {
+		return
+	}
+
+	attrs = append([]slog.Attr{
+		slog.String("module", module),
+		slog.String("resource", resource),
+		slog.String("state", state),
+		slog.String("decision", decision),
+	}, attrs...)
+	l.LogAttrs(ctx, level, msg, attrs...)
+}
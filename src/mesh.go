@@ -0,0 +1,224 @@
+package governance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 服务网格的主机健康状态发布接口，通常由xDS控制面实现，将主机的EDS健康状态设置为UNHEALTHY或HEALTHY
+type MeshHealthPublisher interface {
+	SetHostHealth(ctx context.Context, cluster, host string, healthy bool) error
+}
+
+// Envoy管理接口客户端，用于查询Envoy异常检测已驱逐的主机
+type EnvoyAdmin struct {
+	Addr   string // 管理接口地址，如http://127.0.0.1:15000
+	Client *http.Client
+}
+
+// 创建Envoy管理接口客户端
+func NewEnvoyAdmin(addr string) *EnvoyAdmin {
+	return &EnvoyAdmin{
+		Addr:   addr,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Envoy管理接口/clusters?format=json的返回内容中用到的字段
+type envoyClusters struct {
+	ClusterStatuses []struct {
+		Name         string `json:"name"`
+		HostStatuses []struct {
+			Address struct {
+				SocketAddress struct {
+					Address   string `json:"address"`
+					PortValue int    `json:"port_value"`
+				} `json:"socket_address"`
+			} `json:"address"`
+			HealthStatus struct {
+				FailedOutlierCheck bool `json:"failed_outlier_check"`
+			} `json:"health_status"`
+		} `json:"host_statuses"`
+	} `json:"cluster_statuses"`
+}
+
+// Envoy异常检测已驱逐的主机，key为cluster，value为主机地址集合
+func (e *EnvoyAdmin) Ejected(ctx context.Context) (map[string]map[string]bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.Addr+"/clusters?format=json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("governance: envoy admin returned status %d", resp.StatusCode)
+	}
+
+	var clusters envoyClusters
+	if err := json.NewDecoder(resp.Body).Decode(&clusters); err != nil {
+		return nil, err
+	}
+
+	ejected := make(map[string]map[string]bool)
+	for _, c := range clusters.ClusterStatuses {
+		for _, h := range c.HostStatuses {
+			if !h.HealthStatus.FailedOutlierCheck {
+				continue
+			}
+			if ejected[c.Name] == nil {
+				ejected[c.Name] = make(map[string]bool)
+			}
+			addr := h.Address.SocketAddress
+			ejected[c.Name][net.JoinHostPort(addr.Address, strconv.Itoa(addr.PortValue))] = true
+		}
+	}
+
+	return ejected, nil
+}
+
+// 网格中的主机
+type meshHost struct {
+	cluster string
+	host    string
+}
+
+// 服务网格适配器，将实例熔断的驱逐和恢复同步为网格中主机的健康状态
+// Envoy异常检测已驱逐的主机不再重复标记为不健康，避免两套机制叠加驱逐超过Envoy的max_ejection_percent保护
+type MeshAdapter struct {
+	Breaker   *Breaker
+	Publisher MeshHealthPublisher
+	Admin     *EnvoyAdmin                  // 为空时不查询Envoy的驱逐状态
+	Cluster   func(service string) string  // 服务对应的Envoy cluster名，为空时与服务名相同
+	OnError   func(host string, err error) // 发布健康状态失败时调用
+
+	sync.Mutex
+	open         map[meshHost]bool // 熔断打开的实例
+	published    map[meshHost]bool // 已发布为不健康的实例
+	envoyEjected map[string]map[string]bool
+}
+
+// 创建服务网格适配器，并订阅熔断器的状态变更
+func NewMeshAdapter(breaker *Breaker, publisher MeshHealthPublisher, admin *EnvoyAdmin) *MeshAdapter {
+	m := &MeshAdapter{
+		Breaker:   breaker,
+		Publisher: publisher,
+		Admin:     admin,
+		open:      make(map[meshHost]bool),
+		published: make(map[meshHost]bool),
+	}
+	breaker.Subscribe(m.onChange)
+
+	return m
+}
+
+// 实例熔断状态变更时同步网格中的主机健康状态，非实例维度的rpc资源忽略
+func (m *MeshAdapter) onChange(change StateChange) {
+	key := DecodeResourceKey(change.Resource)
+	if key.Instance == "" || key.Method != "" {
+		return
+	}
+	cluster := key.Service
+	if m.Cluster != nil {
+		cluster = m.Cluster(key.Service)
+	}
+	h := meshHost{cluster: cluster, host: key.Instance}
+
+	m.Lock()
+	if change.To == OpenStatus {
+		m.open[h] = true
+	} else {
+		// 半打开时恢复主机，使探测流量能经过网格到达实例
+		delete(m.open, h)
+	}
+	m.Unlock()
+
+	m.reconcile(context.Background(), h)
+}
+
+// 按熔断状态和Envoy的驱逐状态发布主机h的健康状态
+func (m *MeshAdapter) reconcile(ctx context.Context, h meshHost) error {
+	m.Lock()
+	eject := m.open[h] && !m.envoyEjected[h.cluster][h.host]
+	if eject == m.published[h] {
+		m.Unlock()
+		return nil
+	}
+	m.Unlock()
+
+	err := m.Publisher.SetHostHealth(ctx, h.cluster, h.host, !eject)
+	if err != nil {
+		if m.OnError != nil {
+			m.OnError(h.host, err)
+		}
+		return err
+	}
+
+	m.Lock()
+	if eject {
+		m.published[h] = true
+	} else {
+		delete(m.published, h)
+	}
+	m.Unlock()
+
+	return nil
+}
+
+// 刷新Envoy的驱逐状态并重新同步所有主机，返回第一个错误
+func (m *MeshAdapter) Sync(ctx context.Context) error {
+	var firstErr error
+	if m.Admin != nil {
+		ejected, err := m.Admin.Ejected(ctx)
+		if err != nil {
+			firstErr = err
+		} else {
+			m.Lock()
+			m.envoyEjected = ejected
+			m.Unlock()
+		}
+	}
+
+	m.Lock()
+	hosts := make([]meshHost, 0, len(m.open)+len(m.published))
+	for h := range m.open {
+		hosts = append(hosts, h)
+	}
+	for h := range m.published {
+		if !m.open[h] {
+			hosts = append(hosts, h)
+		}
+	}
+	m.Unlock()
+
+	for _, h := range hosts {
+		if err := m.reconcile(ctx, h); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// 定时同步主机健康状态，ctx结束时返回
+func (m *MeshAdapter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Sync(ctx)
+		}
+	}
+}
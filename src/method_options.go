@@ -0,0 +1,254 @@
+package governance
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// proto/options.proto中MethodGovernance扩展在google.protobuf.MethodOptions中的字段编号
+const MethodGovernanceExtension = 50001
+
+// proto中通过自定义method option声明的治理配置
+type MethodOptions struct {
+	Timeout       int64 // 调用超时时间，单位毫秒
+	Retries       int   // 重试次数
+	FailThreshold int   // 失败阈值
+	SuccThreshold int   // 成功阈值
+	OpenTimeout   int64 // 熔断打开状态的持续时间，单位秒
+}
+
+// gRPC方法描述，LoadDescriptorSet和ParseFileDescriptor从描述符解析，也可由protoc插件生成的代码实现
+type MethodDescriptor interface {
+	// 方法全名，如/pkg.Service/Method
+	FullName() string
+	// 方法上声明的治理option，未声明时返回false
+	GovernanceOptions() (*MethodOptions, bool)
+}
+
+// 从描述符中解析出的gRPC方法
+type descriptorMethod struct {
+	name string
+	opts *MethodOptions
+}
+
+func (m *descriptorMethod) FullName() string { return m.name }
+
+func (m *descriptorMethod) GovernanceOptions() (*MethodOptions, bool) { return m.opts, m.opts != nil }
+
+// 解析protoc --descriptor_set_out生成的FileDescriptorSet，返回其中所有服务的方法
+// 方法上通过proto/options.proto声明的治理option由GovernanceOptions返回
+func LoadDescriptorSet(data []byte) ([]MethodDescriptor, error) {
+	var methods []MethodDescriptor
+	var err error
+	perr := readProto(data, func(f protoField) {
+		// FileDescriptorSet.file
+		if f.num != 1 || err != nil {
+			return
+		}
+		var file []MethodDescriptor
+		if file, err = ParseFileDescriptor(f.message()); err == nil {
+			methods = append(methods, file...)
+		}
+	})
+	if perr != nil {
+		return nil, perr
+	}
+
+	return methods, err
+}
+
+// 解析序列化的FileDescriptorProto，如gRPC server reflection的file_descriptor_proto，返回其中所有服务的方法
+func ParseFileDescriptor(data []byte) ([]MethodDescriptor, error) {
+	var pkg string
+	var services [][]byte
+	err := readProto(data, func(f protoField) {
+		switch f.num {
+		case 2: // package
+			pkg = f.string()
+		case 6: // service
+			services = append(services, f.message())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []MethodDescriptor
+	for _, svc := range services {
+		var name string
+		var raw [][]byte
+		if err := readProto(svc, func(f protoField) {
+			switch f.num {
+			case 1: // name
+				name = f.string()
+			case 2: // method
+				raw = append(raw, f.message())
+			}
+		}); err != nil {
+			return nil, err
+		}
+		if pkg != "" {
+			name = pkg + "." + name
+		}
+		for _, m := range raw {
+			method, err := parseMethodDescriptor(name, m)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, method)
+		}
+	}
+
+	return methods, nil
+}
+
+// 解析服务service下的MethodDescriptorProto
+func parseMethodDescriptor(service string, data []byte) (*descriptorMethod, error) {
+	m := &descriptorMethod{}
+	var options []byte
+	err := readProto(data, func(f protoField) {
+		switch f.num {
+		case 1: // name
+			m.name = "/" + service + "/" + f.string()
+		case 4: // options
+			options = f.message()
+		}
+	})
+	if err != nil || options == nil {
+		return m, err
+	}
+
+	var ext []byte
+	if err := readProto(options, func(f protoField) {
+		if f.num == MethodGovernanceExtension {
+			ext = f.message()
+		}
+	}); err != nil || ext == nil {
+		return m, err
+	}
+
+	m.opts = &MethodOptions{}
+	err = readProto(ext, func(f protoField) {
+		switch f.num {
+		case 1:
+			m.opts.Timeout = f.int64()
+		case 2:
+			m.opts.Retries = int(int32(f.int64()))
+		case 3:
+			m.opts.FailThreshold = int(int32(f.int64()))
+		case 4:
+			m.opts.SuccThreshold = int(int32(f.int64()))
+		case 5:
+			m.opts.OpenTimeout = f.int64()
+		}
+	})
+
+	return m, err
+}
+
+// 方法级治理配置
+type MethodConfig struct {
+	Breaker *Config       // 熔断器配置
+	Timeout time.Duration // 调用超时时间
+	Retries int           // 重试次数
+}
+
+// 根据方法描述生成方法级治理配置，熔断器配置只包含option中设置的字段，其余字段继承服务级和默认配置
+func LoadMethodConfigs(methods []MethodDescriptor) map[string]*MethodConfig {
+	configs := make(map[string]*MethodConfig, len(methods))
+	for _, m := range methods {
+		opts, ok := m.GovernanceOptions()
+		if !ok {
+			continue
+		}
+
+		var config Config
+		if opts.FailThreshold > 0 {
+			config.FailThreshold = opts.FailThreshold
+		}
+		if opts.SuccThreshold > 0 {
+			config.SuccThreshold = opts.SuccThreshold
+		}
+		if opts.OpenTimeout > 0 {
+			config.OpenTimeout = time.Duration(opts.OpenTimeout) * time.Second
+		}
+
+		configs[m.FullName()] = &MethodConfig{
+			Breaker: &config,
+			Timeout: time.Duration(opts.Timeout) * time.Millisecond,
+			Retries: opts.Retries,
+		}
+	}
+
+	return configs
+}
+
+// 将方法级熔断器配置应用到熔断器，同时设置到以方法全名命名的rpc资源和包含服务和方法维度的方法层
+func (breaker *Breaker) ApplyMethodConfigs(configs map[string]*MethodConfig) {
+	for name, c := range configs {
+		if c.Breaker == nil {
+			continue
+		}
+		breaker.SetConfig(name, c.Breaker)
+		if key, ok := methodResourceKey(name); ok {
+			breaker.SetConfig(key.Encode(), c.Breaker)
+		}
+	}
+}
+
+// 将方法全名/pkg.Service/Method转换为包含服务和方法维度的资源标识
+func methodResourceKey(name string) (ResourceKey, bool) {
+	i := strings.LastIndexByte(name, '/')
+	if !strings.HasPrefix(name, "/") || i <= 1 || i == len(name)-1 {
+		return ResourceKey{}, false
+	}
+
+	return ResourceKey{Service: name[1:i], Method: name[i+1:]}, true
+}
+
+// 生效中的方法级配置及按其重试次数创建的重试器
+type methodState struct {
+	config *MethodConfig
+	retry  *Retry
+}
+
+// 应用方法级配置，熔断配置设置到熔断器，超时和重试次数在Execute调用该方法时生效
+func (g *Governance) ApplyMethodConfigs(configs map[string]*MethodConfig) {
+	g.Breaker.ApplyMethodConfigs(configs)
+	for name, c := range configs {
+		state := &methodState{config: c}
+		if c.Retries > 0 {
+			config := RetryConfig{}
+			if g.Retry != nil {
+				config = *g.Retry.Config
+			}
+			config.MaxAttempts = c.Retries + 1
+			state.retry = &Retry{Config: &config, Classifier: retryClassifier}
+		}
+		g.methods.Store(name, state)
+	}
+}
+
+// rpc资源r对应的方法级配置，r为方法全名或包含服务和方法维度的资源标识，未配置时返回空
+func (g *Governance) methodOf(r string) *methodState {
+	if v, ok := g.methods.Load(r); ok {
+		return v.(*methodState)
+	}
+	if k := DecodeResourceKey(r); k.Method != "" {
+		if v, ok := g.methods.Load("/" + k.Service + "/" + k.Method); ok {
+			return v.(*methodState)
+		}
+	}
+
+	return nil
+}
+
+// 按方法级配置的超时时间设置ctx，未配置时返回原ctx
+func (g *Governance) methodTimeout(ctx context.Context, r string) (context.Context, context.CancelFunc) {
+	if m := g.methodOf(r); m != nil && m.config.Timeout > 0 {
+		return context.WithTimeout(ctx, m.config.Timeout)
+	}
+
+	return ctx, func() {}
+}
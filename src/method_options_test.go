@@ -0,0 +1,35 @@
+package governance
+
+import "testing"
+
+type testMethod struct {
+	name string
+	opts *MethodOptions
+}
+
+func (m testMethod) FullName() string { return m.name }
+
+func (m testMethod) GovernanceOptions() (*MethodOptions, bool) { return m.opts, m.opts != nil }
+
+// 方法option只覆盖设置的字段，服务级配置的变更对方法仍然生效，编码的资源标识同样使用方法级配置
+func TestMethodConfigsLayering(t *testing.T) {
+	config := DefaultConfig()
+	breaker := newBreaker(&config)
+	breaker.ApplyMethodConfigs(LoadMethodConfigs([]MethodDescriptor{
+		testMethod{name: "/pkg.Svc/Get", opts: &MethodOptions{SuccThreshold: 4}},
+	}))
+	breaker.SetConfig("pkg.Svc", &Config{FailThreshold: 20})
+
+	for _, r := range []string{
+		ResourceKey{Service: "pkg.Svc", Method: "Get"}.Encode(),
+		ResourceKey{Service: "pkg.Svc", Method: "Get", Instance: "10.0.0.1:80"}.Encode(),
+	} {
+		effective := breaker.ResolveEffectiveConfig(r)
+		if effective.Config.FailThreshold != 20 || effective.Config.SuccThreshold != 4 {
+			t.Errorf("%q: fail_threshold = %d, succ_threshold = %d, want 20 and 4", r, effective.Config.FailThreshold, effective.Config.SuccThreshold)
+		}
+	}
+	if effective := breaker.ResolveEffectiveConfig("/pkg.Svc/Get"); effective.Config.SuccThreshold != 4 {
+		t.Errorf("/pkg.Svc/Get: succ_threshold = %d, want 4", effective.Config.SuccThreshold)
+	}
+}
@@ -0,0 +1,65 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 调用被限流时返回的错误
+var ErrRateLimited = errors.New("governance: rate limited")
+
+// 消息生产者，由sarama、segmentio等kafka客户端适配实现
+type MessageProducer interface {
+	Send(ctx context.Context, topic string, key, value []byte) error
+}
+
+// 受治理的消息生产者，按topic熔断和限流
+type GovernedProducer struct {
+	Producer MessageProducer
+	Breaker  *Breaker
+	Limit    func(topic string) bool // 限流判断，返回false时拒绝发送，为空时不限流
+}
+
+// 发送消息到topic
+func (p *GovernedProducer) Send(ctx context.Context, topic string, key, value []byte) error {
+	if p.Limit != nil && !p.Limit(topic) {
+		return ErrRateLimited
+	}
+
+	return p.Breaker.Execute(ctx, topic, func(ctx context.Context) error {
+		return p.Producer.Send(ctx, topic, key, value)
+	})
+}
+
+// 可暂停的消息消费者，由kafka客户端适配实现
+type PausableConsumer interface {
+	Pause()
+	Resume()
+}
+
+// 根据下游rpc资源r的熔断状态暂停和恢复消费，避免下游不可用时反复消费失败，ctx结束时返回
+func WatchConsumer(ctx context.Context, consumer PausableConsumer, breaker *Breaker, r string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	paused := false
+	for {
+		select {
+		case <-ctx.Done():
+			if paused {
+				consumer.Resume()
+			}
+			return
+		case <-ticker.C:
+			open := breaker.getStatus(r) == OpenStatus
+			if open && !paused {
+				consumer.Pause()
+				paused = true
+			} else if !open && paused {
+				consumer.Resume()
+				paused = false
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package governance
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	uuidPattern    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexPattern     = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	numberPattern  = regexp.MustCompile(`^[0-9]+$`)
+	sqlVerbPattern = regexp.MustCompile(`(?i)^\s*(select|insert|update|delete|replace)\b`)
+	sqlFromPattern = regexp.MustCompile("(?i)\\b(?:from|into|update)\\s+[`\"]?([\\w.]+)")
+)
+
+// 将http路径中的ID段替换为占位符，如/users/123/orders变为/users/{id}/orders
+func NormalizeHTTPPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		switch {
+		case seg == "":
+		case numberPattern.MatchString(seg):
+			segments[i] = "{id}"
+		case uuidPattern.MatchString(seg):
+			segments[i] = "{uuid}"
+		case hexPattern.MatchString(seg):
+			segments[i] = "{hash}"
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// 规范化gRPC方法名为/pkg.Service/Method格式
+func NormalizeGRPCMethod(method string) string {
+	method = strings.TrimSpace(method)
+	if !strings.HasPrefix(method, "/") {
+		method = "/" + method
+	}
+
+	return method
+}
+
+// 将SQL语句规范化为操作和主表，如SELECT users
+func NormalizeSQL(stmt string) string {
+	verb := sqlVerbPattern.FindStringSubmatch(stmt)
+	if verb == nil {
+		return "OTHER"
+	}
+
+	name := strings.ToUpper(verb[1])
+	if table := sqlFromPattern.FindStringSubmatch(stmt); table != nil {
+		name += " " + strings.ToLower(table[1])
+	}
+
+	return name
+}
+
+// 将Redis命令规范化为大写的命令名，忽略键和参数
+func NormalizeRedis(args ...string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	return strings.ToUpper(args[0])
+}
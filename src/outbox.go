@@ -0,0 +1,166 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 缓存队列已满时返回的错误
+var ErrOutboxFull = errors.New("governance: outbox is full")
+
+// 熔断打开时缓存的写调用
+type OutboxEntry struct {
+	Resource string // rpc资源名
+	Payload  []byte // 写入内容
+}
+
+// 缓存队列的存储，可实现为本地文件等持久化存储
+type OutboxStore interface {
+	// 写入队尾，队列已满时返回false
+	Push(e OutboxEntry) bool
+	// 取出队首
+	Pop() (OutboxEntry, bool)
+	// 放回队首
+	Unpop(e OutboxEntry)
+	Len() int
+}
+
+// 基于内存的有界缓存队列
+type memoryOutboxStore struct {
+	sync.Mutex
+	size    int
+	entries []OutboxEntry
+}
+
+// 创建容量为size的内存缓存队列
+func NewMemoryOutboxStore(size int) OutboxStore {
+	return &memoryOutboxStore{size: size}
+}
+
+func (s *memoryOutboxStore) Push(e OutboxEntry) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.entries) >= s.size {
+		return false
+	}
+	s.entries = append(s.entries, e)
+
+	return true
+}
+
+func (s *memoryOutboxStore) Pop() (OutboxEntry, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.entries) == 0 {
+		return OutboxEntry{}, false
+	}
+	e := s.entries[0]
+	s.entries = s.entries[1:]
+
+	return e, true
+}
+
+func (s *memoryOutboxStore) Unpop(e OutboxEntry) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.entries = append([]OutboxEntry{e}, s.entries...)
+}
+
+func (s *memoryOutboxStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.entries)
+}
+
+// 非关键写调用的溢出队列，熔断打开时缓存写调用，熔断关闭后重放
+type Outbox struct {
+	Breaker *Breaker
+	Store   OutboxStore
+	Write   func(ctx context.Context, e OutboxEntry) error // 实际的写调用
+
+	replayed int64 // 重放成功的次数
+	dropped  int64 // 队列已满丢弃的次数
+}
+
+// 创建溢出队列
+func NewOutbox(breaker *Breaker, store OutboxStore, write func(ctx context.Context, e OutboxEntry) error) *Outbox {
+	return &Outbox{
+		Breaker: breaker,
+		Store:   store,
+		Write:   write,
+	}
+}
+
+// 写入rpc资源r，被熔断拒绝时缓存到队列
+func (o *Outbox) Execute(ctx context.Context, r string, payload []byte) error {
+	e := OutboxEntry{Resource: r, Payload: payload}
+	err := o.Breaker.Execute(ctx, r, func(ctx context.Context) error {
+		return o.Write(ctx, e)
+	})
+	if !errors.Is(err, ErrBreakerOpen) {
+		return err
+	}
+
+	if !o.Store.Push(e) {
+		atomic.AddInt64(&o.dropped, 1)
+		return ErrOutboxFull
+	}
+
+	return nil
+}
+
+// 重放缓存的写调用，遇到熔断未关闭或重放失败时停止
+func (o *Outbox) Flush(ctx context.Context) {
+	for {
+		e, ok := o.Store.Pop()
+		if !ok {
+			return
+		}
+
+		if o.Breaker.State(e.Resource) != CloseStatus {
+			o.Store.Unpop(e)
+			return
+		}
+
+		err := o.Breaker.Execute(ctx, e.Resource, func(ctx context.Context) error {
+			return o.Write(ctx, e)
+		})
+		if err != nil {
+			o.Store.Unpop(e)
+			return
+		}
+		atomic.AddInt64(&o.replayed, 1)
+	}
+}
+
+// 定时重放缓存的写调用，ctx结束时返回
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.Flush(ctx)
+		}
+	}
+}
+
+// 重放成功的次数
+func (o *Outbox) Replayed() int64 {
+	return atomic.LoadInt64(&o.replayed)
+}
+
+// 队列已满丢弃的次数
+func (o *Outbox) Dropped() int64 {
+	return atomic.LoadInt64(&o.dropped)
+}
@@ -0,0 +1,55 @@
+package governance
+
+import (
+	"context"
+	"net/http"
+)
+
+// 调试用的请求头
+const (
+	RouteOverrideHeader = "x-governance-route" // 指定路由的服务版本
+	ForceOverrideHeader = "x-governance-force" // 值为pass时跳过熔断
+)
+
+// 强制放行
+const ForcePass = "pass"
+
+// 请求级的调试覆盖
+type Overrides struct {
+	Route string // 指定路由的服务版本
+	Force string // 强制行为
+}
+
+type overridesKey struct{}
+
+// 从请求头或gRPC metadata中解析调试覆盖
+func ParseOverrides(get func(key string) string) Overrides {
+	return Overrides{
+		Route: get(RouteOverrideHeader),
+		Force: get(ForceOverrideHeader),
+	}
+}
+
+// 将调试覆盖放入ctx，调用方需确保请求满足信任条件
+func WithOverrides(ctx context.Context, o Overrides) context.Context {
+	return context.WithValue(ctx, overridesKey{}, o)
+}
+
+// 获取ctx中的调试覆盖
+func overridesFrom(ctx context.Context) (Overrides, bool) {
+	o, ok := ctx.Value(overridesKey{}).(Overrides)
+	return o, ok
+}
+
+// http中间件，请求满足信任条件trusted时才解析调试请求头
+func OverrideHandler(next http.Handler, trusted func(r *http.Request) bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trusted(r) {
+			o := ParseOverrides(r.Header.Get)
+			if o.Route != "" || o.Force != "" {
+				r = r.WithContext(WithOverrides(r.Context(), o))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
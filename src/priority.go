@@ -0,0 +1,95 @@
+package governance
+
+import (
+	"context"
+	"net/http"
+)
+
+// 传播请求优先级的请求头，也可作为gRPC metadata的key
+const PriorityHeader = "x-governance-priority"
+
+// 请求优先级，数值越大越重要，零值表示未设置，按PriorityNormal处理
+type Priority int
+
+const (
+	PrioritySheddable Priority = iota + 1 // 可丢弃，过载时最先被拒绝
+	PriorityNormal                        // 普通
+	PriorityCritical                      // 关键，排队时优先处理
+)
+
+var priorityNames = map[Priority]string{
+	PrioritySheddable: "sheddable",
+	PriorityNormal:    "normal",
+	PriorityCritical:  "critical",
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+
+	return priorityNames[PriorityNormal]
+}
+
+type priorityKey struct{}
+
+// 为请求设置优先级，下游调用的排队和限流按此优先级处理
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// 获取请求的优先级，未设置时为PriorityNormal
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p != 0 {
+		return p
+	}
+
+	return PriorityNormal
+}
+
+// 从请求头或gRPC metadata中解析优先级，未设置或无法识别时返回0
+func ParsePriority(get func(key string) string) Priority {
+	v := get(PriorityHeader)
+	for p, name := range priorityNames {
+		if name == v {
+			return p
+		}
+	}
+
+	return 0
+}
+
+// 将请求的优先级写入下游调用的请求头或gRPC metadata，使下游按相同的优先级处理
+func InjectPriority(ctx context.Context, set func(key, value string)) {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p != 0 {
+		set(PriorityHeader, p.String())
+	}
+}
+
+// http中间件，从请求头中解析上游传递的优先级
+func PriorityHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := ParsePriority(r.Header.Get); p != 0 {
+			r = r.WithContext(WithPriority(r.Context(), p))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 在下游http请求中传递优先级的传输层
+type PriorityTransport struct {
+	Base http.RoundTripper // 为空时使用http.DefaultTransport
+}
+
+func (t *PriorityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if _, ok := req.Context().Value(priorityKey{}).(Priority); ok && req.Header.Get(PriorityHeader) == "" {
+		req = req.Clone(req.Context())
+		InjectPriority(req.Context(), req.Header.Set)
+	}
+
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,129 @@
+package governance
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// protobuf数据格式错误
+var ErrInvalidProto = errors.New("governance: invalid protobuf data")
+
+// protobuf字段的编码类型
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// protobuf编码器，只支持proto/state.proto和proto/control.proto用到的字段类型，零值字段按proto3约定省略，optional字段见optionalBool
+type protoWriter struct {
+	buf []byte
+}
+
+func (w *protoWriter) tag(field, wireType int) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (w *protoWriter) int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	w.tag(field, wireVarint)
+	w.buf = binary.AppendUvarint(w.buf, uint64(v))
+}
+
+func (w *protoWriter) bool(field int, v bool) {
+	if v {
+		w.int64(field, 1)
+	}
+}
+
+// 编码optional bool字段，为空时省略，false也会编码以区分未设置
+func (w *protoWriter) optionalBool(field int, v *bool) {
+	if v == nil {
+		return
+	}
+	w.tag(field, wireVarint)
+	w.buf = binary.AppendUvarint(w.buf, 0)
+	if *v {
+		w.buf[len(w.buf)-1] = 1
+	}
+}
+
+func (w *protoWriter) double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	w.tag(field, wireFixed64)
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, math.Float64bits(v))
+}
+
+func (w *protoWriter) bytes(field int, v []byte) {
+	w.tag(field, wireBytes)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *protoWriter) string(field int, v string) {
+	if v != "" {
+		w.bytes(field, []byte(v))
+	}
+}
+
+// protobuf解码器中的一个字段
+type protoField struct {
+	num    int
+	varint uint64
+	fixed  uint64
+	data   []byte
+}
+
+func (f protoField) int64() int64    { return int64(f.varint) }
+func (f protoField) bool() bool      { return f.varint != 0 }
+func (f protoField) double() float64 { return math.Float64frombits(f.fixed) }
+func (f protoField) string() string  { return string(f.data) }
+func (f protoField) message() []byte { return f.data }
+
+// 依次解码data中的字段并调用fn，忽略未知字段由fn决定
+func readProto(data []byte, fn func(f protoField)) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrInvalidProto
+		}
+		data = data[n:]
+
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return ErrInvalidProto
+			}
+			f.varint, data = v, data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return ErrInvalidProto
+			}
+			f.fixed, data = binary.LittleEndian.Uint64(data), data[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < l {
+				return ErrInvalidProto
+			}
+			f.data, data = data[n:n+int(l)], data[n+int(l):]
+		case wireFixed32:
+			if len(data) < 4 {
+				return ErrInvalidProto
+			}
+			f.fixed, data = uint64(binary.LittleEndian.Uint32(data)), data[4:]
+		default:
+			return ErrInvalidProto
+		}
+		fn(f)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// 反向代理配置
+type ProxyConfig struct {
+	Target     string           `toml:"target"`     // 被代理服务的地址，如http://127.0.0.1:8080
+	Service    string           `toml:"service"`    // 被代理服务的服务名
+	Governance GovernanceConfig `toml:"governance"` // 治理配置
+	Routes     []GatewayRoute   `toml:"routes"`     // REST路由到gRPC方法的映射，被代理服务为gRPC-Gateway时与gRPC调用共用rpc资源
+}
+
+// 带治理能力的反向代理，为无法引入本库的服务提供保护
+type Proxy struct {
+	Config     *ProxyConfig
+	Governance *Governance
+	Mapper     *GatewayMapper
+	proxy      *httputil.ReverseProxy
+}
+
+// 创建反向代理，按服务名和请求路径作为rpc资源，路径映射到gRPC方法时按gRPC方法作为rpc资源
+func NewProxy(config *ProxyConfig) (*Proxy, error) {
+	target, err := url.Parse(config.Target)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := NewGatewayMapper(config.Routes...)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Proxy{
+		Config:     config,
+		Governance: New(WithConfig(&config.Governance)),
+		Mapper:     mapper,
+	}
+	p.proxy = httputil.NewSingleHostReverseProxy(target)
+	p.proxy.Transport = &proxyTransport{proxy: p, base: http.DefaultTransport}
+	p.proxy.ErrorHandler = proxyErrorHandler
+
+	return p, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.proxy.ServeHTTP(w, r)
+}
+
+// 在治理保护下转发请求
+type proxyTransport struct {
+	proxy *Proxy
+	base  http.RoundTripper
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := t.proxy.Mapper.ResourceKey(t.proxy.Config.Service, req)
+
+	ctx := req.Context()
+	// 请求体只能读取一次，无法重新获取时不重试
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		ctx = WithoutRetry(ctx)
+	}
+
+	var resp *http.Response
+	attempts := 0
+	err := t.proxy.Governance.Execute(ctx, resource, func(ctx context.Context) error {
+		// 上游请求使用调用方的context，Execute返回时会取消ctx，响应体需在之后由ReverseProxy读取
+		// 收到响应头之前ctx结束时取消上游请求，之后由响应体的Close取消
+		rctx, rcancel := context.WithCancel(req.Context())
+		stop := context.AfterFunc(ctx, rcancel)
+		out := req.WithContext(rctx)
+		if attempts > 0 {
+			// 重试前关闭上次尝试的响应，并重新获取请求体
+			if resp != nil {
+				resp.Body.Close()
+				resp = nil
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					stop()
+					rcancel()
+					return err
+				}
+				out.Body = body
+			}
+		}
+		attempts++
+
+		r, err := t.base.RoundTrip(out)
+		if !stop() {
+			// ctx已结束，上游请求已被取消
+			if err == nil {
+				r.Body.Close()
+			}
+			return ctx.Err()
+		}
+		if err != nil {
+			rcancel()
+			return err
+		}
+		r.Body = &cancelBody{ReadCloser: r.Body, cancel: rcancel}
+		resp = r
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return &HTTPStatusError{Code: resp.StatusCode}
+		}
+		return nil
+	})
+
+	// 上游返回的错误状态码原样返回给调用方
+	var se *HTTPStatusError
+	if errors.As(err, &se) && resp != nil {
+		return resp, nil
+	}
+	if err != nil {
+		// 重试被熔断或限流拒绝时，resp为之前尝试的响应，需关闭
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// 关闭时取消上游请求context的响应体
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
+
+// 将治理拒绝转换为对应的http状态码
+func proxyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ErrBreakerOpen):
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case errors.Is(err, ErrRateLimited):
+		w.WriteHeader(http.StatusTooManyRequests)
+	default:
+		w.WriteHeader(http.StatusBadGateway)
+	}
+}
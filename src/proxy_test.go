@@ -0,0 +1,93 @@
+package governance
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Execute返回后ReverseProxy才读取响应体，响应体不能随单次尝试的context取消
+func TestProxyBodyOutlivesAttempt(t *testing.T) {
+	body := strings.Repeat("x", 1<<16)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 先返回响应头，稍后再写响应体
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(w, body)
+	}))
+	defer upstream.Close()
+
+	p, err := NewProxy(&ProxyConfig{
+		Target:  upstream.URL,
+		Service: "svc",
+		Governance: GovernanceConfig{
+			Breaker: DefaultConfig(),
+			Lazy:    true,
+			Retry:   &RetryConfig{MaxAttempts: 2, AttemptTimeout: 1000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewProxy: %v", err)
+	}
+	front := httptest.NewServer(p)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/get")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil || len(got) != len(body) {
+		t.Fatalf("read %d bytes, err = %v, want %d bytes", len(got), err, len(body))
+	}
+}
+
+type closeTracker struct {
+	io.Reader
+	closed *int32
+}
+
+func (c closeTracker) Close() error {
+	atomic.AddInt32(c.closed, 1)
+	return nil
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// 重试被熔断拒绝时返回nil响应和错误，并关闭之前尝试的响应
+func TestProxyRetryRejectedClosesResponse(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 1
+	config.OpenTimeout = time.Hour
+	p := &Proxy{
+		Config: &ProxyConfig{Service: "svc"},
+		Governance: New(WithConfig(&GovernanceConfig{
+			Breaker: config,
+			Lazy:    true,
+			Retry:   &RetryConfig{MaxAttempts: 2},
+		})),
+	}
+	p.Mapper, _ = NewGatewayMapper()
+
+	var closed int32
+	transport := &proxyTransport{proxy: p, base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: closeTracker{Reader: strings.NewReader(""), closed: &closed}}, nil
+	})}
+	req := httptest.NewRequest(http.MethodGet, "http://svc/get", nil)
+	resp, err := transport.RoundTrip(req)
+	if resp != nil || err == nil {
+		t.Fatalf("RoundTrip = %v, %v, want nil response and an error", resp, err)
+	}
+	if atomic.LoadInt32(&closed) != 1 {
+		t.Fatalf("closed %d responses, want 1", closed)
+	}
+}
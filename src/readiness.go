@@ -0,0 +1,137 @@
+package governance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// 就绪检查路径
+const ReadinessPath = "/governance/ready"
+
+var (
+	// 规则尚未加载
+	ErrRulesNotLoaded = errors.New("governance: rules not loaded")
+	// 熔断器后台定时任务未运行
+	ErrBreakerNotTicking = errors.New("governance: breaker background task not running")
+)
+
+// 就绪检查项，返回空表示已就绪
+type ReadinessCheck func() error
+
+// 启动就绪检查，所有检查项通过之前报告未就绪，避免治理生效前接收流量
+// 所有检查项通过后不再重复检查，进程生命周期内只从未就绪变为就绪一次
+type Readiness struct {
+	sync.Mutex
+	names  []string
+	checks map[string]ReadinessCheck
+	ready  bool
+}
+
+// 创建就绪检查
+func NewReadiness() *Readiness {
+	return &Readiness{checks: make(map[string]ReadinessCheck)}
+}
+
+// 添加名为name的检查项
+func (rd *Readiness) Add(name string, check ReadinessCheck) {
+	rd.Lock()
+	defer rd.Unlock()
+
+	if _, ok := rd.checks[name]; !ok {
+		rd.names = append(rd.names, name)
+	}
+	rd.checks[name] = check
+	rd.ready = false
+}
+
+// 执行检查，返回未通过的检查项及原因
+func (rd *Readiness) Check() map[string]string {
+	rd.Lock()
+	defer rd.Unlock()
+
+	if rd.ready {
+		return nil
+	}
+
+	failed := make(map[string]string)
+	for _, name := range rd.names {
+		if err := rd.checks[name](); err != nil {
+			failed[name] = err.Error()
+		}
+	}
+	if len(failed) == 0 {
+		rd.ready = true
+		return nil
+	}
+
+	return failed
+}
+
+// 判断是否已就绪
+func (rd *Readiness) Ready() bool {
+	return len(rd.Check()) == 0
+}
+
+// 就绪时返回200，否则返回503和未通过的检查项
+func (rd *Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	failed := rd.Check()
+	if len(failed) == 0 {
+		w.Write([]byte("ready\n"))
+		return
+	}
+
+	// 需在WriteHeader之前设置响应头
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(failed)
+}
+
+// 服务发现已为services中的每个服务下发实例
+func InstancesReady(instances *StaticInstances, services ...string) ReadinessCheck {
+	return func() error {
+		for _, service := range services {
+			if len(instances.Instances(service)) == 0 {
+				return fmt.Errorf("governance: no instances discovered for service %s", service)
+			}
+		}
+		return nil
+	}
+}
+
+// 规则管理器已应用过规则
+func RulesReady(m *RuleManager) ReadinessCheck {
+	return func() error {
+		if len(m.Versions()) == 0 {
+			return ErrRulesNotLoaded
+		}
+		return nil
+	}
+}
+
+// 校验通过的规则数据，用于在加载规则前确认配置有效
+func RulesValid(data func() ([]byte, error)) ReadinessCheck {
+	return func() error {
+		b, err := data()
+		if err != nil {
+			return err
+		}
+		if _, errs := ValidateRules(b); len(errs) > 0 {
+			return errs[0]
+		}
+		return nil
+	}
+}
+
+// 熔断器的后台定时任务已启动且仍在正常执行，不启动后台goroutine的熔断器总是就绪
+func BreakerReady(breaker *Breaker) ReadinessCheck {
+	return func() error {
+		info := breaker.DebugInfo()
+		if breaker.lazy || (info.LastTick != 0 && info.TickHealthy) {
+			return nil
+		}
+		return ErrBreakerNotTicking
+	}
+}
@@ -0,0 +1,196 @@
+package governance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 飞行记录器接口路径，GET返回内存中的记录，POST将记录写入文件
+const DebugRecorderPath = "/debug/governance/recorder"
+
+// 飞行记录器配置
+type RecorderConfig struct {
+	Window   int64  `toml:"window"`   // 保留的时长，单位秒
+	Interval int64  `toml:"interval"` // 采样间隔，单位秒，为0时每10秒采样
+	Dir      string `toml:"dir"`      // 写入文件的目录，为空时使用系统临时目录
+}
+
+// 飞行记录器的一帧，即某一时刻所有rpc资源的状态
+type RecorderFrame struct {
+	Time      time.Time                `json:"time"`
+	Resources []ResourceState          `json:"resources"`
+	InFlight  map[string]int64         `json:"inflight,omitempty"`
+	ErrorRate map[string]float64       `json:"error_rate,omitempty"` // 采样间隔内的错误率
+	P99       map[string]time.Duration `json:"p99,omitempty"`        // 采样间隔内的p99耗时
+}
+
+// 飞行记录器，在内存环形缓冲区中保留最近一段时间的rpc资源状态，事故后可通过信号或接口写入文件
+// 弥补监控采集间隔过粗时事故前后数据缺失的问题
+type FlightRecorder struct {
+	Breaker  *Breaker
+	Interval time.Duration // 采样间隔
+	Dir      string        // 写入文件的目录，为空时使用系统临时目录
+
+	sync.Mutex
+	frames []RecorderFrame
+	next   int  // 下一帧写入的位置
+	full   bool // 缓冲区是否已写满
+}
+
+// 默认的采样间隔
+const defaultRecorderInterval = 10 * time.Second
+
+// 创建飞行记录器，保留最近window内每隔interval采样的状态，interval为0时每10秒采样
+func NewFlightRecorder(breaker *Breaker, window, interval time.Duration) *FlightRecorder {
+	if interval <= 0 {
+		interval = defaultRecorderInterval
+	}
+	size := int(window / interval)
+	if size < 1 {
+		size = 1
+	}
+
+	return &FlightRecorder{
+		Breaker:  breaker,
+		Interval: interval,
+		frames:   make([]RecorderFrame, size),
+	}
+}
+
+// 采样一帧
+func (rec *FlightRecorder) Record() {
+	frame := RecorderFrame{
+		Time:      time.Now(),
+		Resources: rec.Breaker.Snapshot(),
+		InFlight:  rec.Breaker.InFlight(),
+		ErrorRate: make(map[string]float64),
+		P99:       make(map[string]time.Duration),
+	}
+	// 滑动窗口按秒统计，采样间隔不足1秒时按1秒计
+	window := rec.Interval
+	if window < time.Second {
+		window = time.Second
+	}
+	rec.Breaker.windows.Range(func(k, _ interface{}) bool {
+		r := k.(string)
+		if rec.Breaker.Requests(r, window) > 0 {
+			frame.ErrorRate[r] = rec.Breaker.ErrorRate(r, window)
+			frame.P99[r] = rec.Breaker.P99(r, window)
+		}
+		return true
+	})
+
+	rec.Lock()
+	defer rec.Unlock()
+	rec.frames[rec.next] = frame
+	rec.next = (rec.next + 1) % len(rec.frames)
+	if rec.next == 0 {
+		rec.full = true
+	}
+}
+
+// 按时间顺序返回缓冲区中的所有帧
+func (rec *FlightRecorder) Frames() []RecorderFrame {
+	rec.Lock()
+	defer rec.Unlock()
+
+	if !rec.full {
+		return append([]RecorderFrame(nil), rec.frames[:rec.next]...)
+	}
+	frames := make([]RecorderFrame, 0, len(rec.frames))
+	frames = append(frames, rec.frames[rec.next:]...)
+
+	return append(frames, rec.frames[:rec.next]...)
+}
+
+// 将所有帧以每行一个JSON的格式写入w
+func (rec *FlightRecorder) Dump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, frame := range rec.Frames() {
+		if err := enc.Encode(frame); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 将所有帧写入Dir下以当前时间命名的文件，返回文件路径
+func (rec *FlightRecorder) DumpFile() (string, error) {
+	dir := rec.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	path := filepath.Join(dir, fmt.Sprintf("governance-%s.jsonl", time.Now().Format("20060102-150405")))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := rec.Dump(f); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	return path, f.Close()
+}
+
+// 定时采样，ctx结束时返回
+func (rec *FlightRecorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(rec.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rec.Record()
+		}
+	}
+}
+
+// 收到sigs中的信号时将记录写入文件，如syscall.SIGUSR1，写入结果通过done回调，ctx结束时返回
+func (rec *FlightRecorder) DumpOnSignal(ctx context.Context, done func(path string, err error), sigs ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			path, err := rec.DumpFile()
+			if done != nil {
+				done(path, err)
+			}
+		}
+	}
+}
+
+// GET以JSON Lines格式返回所有帧，POST将记录写入文件并返回文件路径
+func (rec *FlightRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		rec.Dump(w)
+	case http.MethodPost:
+		path, err := rec.DumpFile()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]string{"path": path})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,199 @@
+package governance
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 录制的一次调用
+type ReplayRecord struct {
+	Time     time.Time `json:"time"`        // 调用开始的时间
+	Resource string    `json:"resource"`    // rpc资源名
+	Duration int64     `json:"duration_ms"` // 调用耗时，单位毫秒
+	Error    string    `json:"error"`       // 调用返回的错误，为空表示成功
+}
+
+// 回放后单个rpc资源的结果
+type ReplayResult struct {
+	Resource    string      `json:"resource"`
+	Requests    int64       `json:"requests"`     // 录制的调用数
+	Failures    int64       `json:"failures"`     // 放行的调用中失败的调用数
+	Rejected    int64       `json:"rejected"`     // 会被熔断拒绝的调用数
+	RateLimited int64       `json:"rate_limited"` // 会被限流拒绝的调用数
+	Trips       []time.Time `json:"trips"`        // 熔断打开的时间
+	LimitedAt   time.Time   `json:"limited_at"`   // 首次被限流拒绝的时间，零值表示未被限流
+}
+
+// 回放报告
+type ReplayReport struct {
+	Requests    int64          `json:"requests"`
+	Rejected    int64          `json:"rejected"`
+	RateLimited int64          `json:"rate_limited"`
+	Trips       int64          `json:"trips"`
+	Resources   []ReplayResult `json:"resources"` // 按资源名排序
+}
+
+// 从JSON中读取录制的调用，支持JSON数组或每行一个JSON对象
+func LoadReplayJSON(r io.Reader) ([]ReplayRecord, error) {
+	dec := json.NewDecoder(r)
+	var records []ReplayRecord
+	for {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(v) > 0 && v[0] == '[' {
+			var batch []ReplayRecord
+			if err := json.Unmarshal(v, &batch); err != nil {
+				return nil, err
+			}
+			records = append(records, batch...)
+			continue
+		}
+		var record ReplayRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// 从CSV中读取录制的调用，列依次为时间(RFC3339)、rpc资源名、耗时(毫秒)和错误，首行为表头时跳过
+func LoadReplayCSV(r io.Reader) ([]ReplayRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > 0 && len(rows[0]) > 0 && rows[0][0] == "time" {
+		rows = rows[1:]
+	}
+
+	records := make([]ReplayRecord, 0, len(rows))
+	for i, row := range rows {
+		if len(row) < 3 {
+			return nil, fmt.Errorf("governance: replay csv line %d: expected at least 3 columns", i+1)
+		}
+		t, err := time.Parse(time.RFC3339Nano, row[0])
+		if err != nil {
+			return nil, fmt.Errorf("governance: replay csv line %d: %v", i+1, err)
+		}
+		d, err := strconv.ParseInt(row[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("governance: replay csv line %d: %v", i+1, err)
+		}
+		record := ReplayRecord{Time: t, Resource: row[1], Duration: d}
+		if len(row) > 3 {
+			record.Error = row[3]
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// 回放中的事件，调用开始时判断是否放行，调用结束时按结果更新熔断状态
+type replayEvent struct {
+	at     time.Time
+	record int
+	finish bool
+}
+
+// 按配置config和规则集rules回放录制的调用，报告会被拒绝的调用和熔断打开的时间，用于离线调整阈值
+// 回放使用录制的时间而非真实时间，不启动后台goroutine；延迟模式的限流按直接拒绝处理
+func Replay(config *GovernanceConfig, rules *RuleSet, records []ReplayRecord) *ReplayReport {
+	breakerConfig := config.Breaker
+	breaker := newBreaker(&breakerConfig)
+	breaker.lazy = true
+	var now time.Time
+	breaker.now = func() time.Time { return now }
+	if rules != nil {
+		for r, c := range rules.Breakers {
+			breaker.Configs[r] = c
+		}
+		breaker.invalidateConfigs()
+	}
+	var limiter *Limiter
+	if config.Limiter != nil {
+		limiter = NewLimiter(config.Limiter)
+	}
+
+	events := make([]replayEvent, 0, 2*len(records))
+	for i, record := range records {
+		events = append(events, replayEvent{at: record.Time, record: i})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	results := make(map[string]*ReplayResult)
+	for i := 0; i < len(events); i++ {
+		e := events[i]
+		now = e.at
+		record := records[e.record]
+		res, ok := results[record.Resource]
+		if !ok {
+			res = &ReplayResult{Resource: record.Resource}
+			results[record.Resource] = res
+		}
+
+		if !e.finish {
+			res.Requests++
+			if limiter != nil && !limiter.allowAt(record.Resource, 1, now) {
+				if res.RateLimited == 0 {
+					res.LimitedAt = now
+				}
+				res.RateLimited++
+				continue
+			}
+			if !breaker.allow(context.Background(), record.Resource) {
+				res.Rejected++
+				continue
+			}
+			// 放行的调用在结束时更新熔断状态，按结束时间插入事件队列
+			finish := replayEvent{at: record.Time.Add(time.Duration(record.Duration) * time.Millisecond), record: e.record, finish: true}
+			j := i + 1 + sort.Search(len(events)-i-1, func(k int) bool { return events[i+1+k].at.After(finish.at) })
+			events = append(events, replayEvent{})
+			copy(events[j+1:], events[j:])
+			events[j] = finish
+			continue
+		}
+
+		var err error
+		if record.Error != "" {
+			err = errors.New(record.Error)
+		}
+		before := breaker.getStatus(record.Resource)
+		switch breaker.classify(record.Resource, err) {
+		case OutcomeFailure:
+			res.Failures++
+			breaker.setFail(record.Resource, err)
+		case OutcomeSuccess:
+			breaker.setSucc(record.Resource)
+		}
+		if before != OpenStatus && breaker.getStatus(record.Resource) == OpenStatus {
+			res.Trips = append(res.Trips, now)
+		}
+	}
+
+	report := &ReplayReport{}
+	for _, res := range results {
+		report.Requests += res.Requests
+		report.Rejected += res.Rejected
+		report.RateLimited += res.RateLimited
+		report.Trips += int64(len(res.Trips))
+		report.Resources = append(report.Resources, *res)
+	}
+	sort.Slice(report.Resources, func(i, j int) bool { return report.Resources[i].Resource < report.Resources[j].Resource })
+
+	return report
+}
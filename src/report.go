@@ -0,0 +1,372 @@
+package governance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 报告中p99趋势的分段数
+const reportTrendPoints = 12
+
+// 报告数据的默认保留时长，足够生成日报
+const defaultReportRetention = 48 * time.Hour
+
+// 报告中的一次熔断
+type ReportTrip struct {
+	Time   time.Time `json:"time"`
+	Reason string    `json:"reason"`
+}
+
+// 报告中的拒绝原因及次数
+type ReportReason struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// 单个rpc资源的报告
+type ResourceReport struct {
+	Resource   string          `json:"resource"`
+	Trips      []ReportTrip    `json:"trips"`
+	Rejections int64           `json:"rejections"`
+	TopReasons []ReportReason  `json:"top_reasons"` // 按次数降序的拒绝原因，最多5个
+	P99Trend   []time.Duration `json:"p99_trend"`   // 周期内各分段的最大p99，没有采样的分段为0
+}
+
+// 报告中的一次规则变更
+type ReportRuleChange struct {
+	Time        time.Time `json:"time"`
+	FromVersion int64     `json:"from_version"`
+	ToVersion   int64     `json:"to_version"`
+	Rollback    bool      `json:"rollback"`
+	Resources   []string  `json:"resources"`
+}
+
+// 周期性的治理报告
+type Report struct {
+	From        time.Time          `json:"from"`
+	To          time.Time          `json:"to"`
+	Resources   []ResourceReport   `json:"resources"`
+	RuleChanges []ReportRuleChange `json:"rule_changes"`
+}
+
+// 带时间的拒绝记录
+type reportRejection struct {
+	time     time.Time
+	resource string
+	reason   string
+}
+
+// 带时间的p99采样
+type reportSample struct {
+	time     time.Time
+	resource string
+	p99      time.Duration
+}
+
+// 带时间的熔断记录
+type reportTrip struct {
+	resource string
+	trip     ReportTrip
+}
+
+// 治理报告生成器，收集熔断、拒绝、p99和规则变更，按周期生成JSON或Markdown报告，用于推送到值班群
+// 拒绝按资源、原因和分钟聚合存储，内存占用与资源数和保留时长成正比
+type Reporter struct {
+	Breaker   *Breaker
+	Retention time.Duration // 数据保留时长，默认48小时
+
+	sync.Mutex
+	trips       []reportTrip
+	rejections  map[reportRejection]int64 // 按分钟聚合的拒绝次数
+	samples     []reportSample
+	ruleChanges []ReportRuleChange
+}
+
+// 创建报告生成器，订阅熔断器的状态变更和拒绝，rules和limiter不为空时同时收集规则变更和限流拒绝
+// 需在开始调用前创建，已设置的OnReject和AuditLog回调会保留
+func NewReporter(breaker *Breaker, rules *RuleManager, limiter *Limiter) *Reporter {
+	rep := &Reporter{
+		Breaker:    breaker,
+		Retention:  defaultReportRetention,
+		rejections: make(map[reportRejection]int64),
+	}
+
+	breaker.Subscribe(func(change StateChange) {
+		if change.To == OpenStatus {
+			rep.Lock()
+			rep.trips = append(rep.trips, reportTrip{resource: change.Resource, trip: ReportTrip{Time: change.Time, Reason: change.Reason}})
+			rep.Unlock()
+		}
+	})
+	prevReject := breaker.OnReject
+	breaker.OnReject = func(r, reason string) {
+		if prevReject != nil {
+			prevReject(r, reason)
+		}
+		rep.reject(r, reason)
+	}
+	if limiter != nil {
+		prevLimit := limiter.OnReject
+		limiter.OnReject = func(key string) {
+			if prevLimit != nil {
+				prevLimit(key)
+			}
+			rep.reject(key, "rate limited")
+		}
+	}
+	if rules != nil {
+		rules.Lock()
+		prevAudit := rules.AuditLog
+		rules.AuditLog = func(entry AuditEntry) {
+			if prevAudit != nil {
+				prevAudit(entry)
+			}
+			rep.ruleChange(entry)
+		}
+		rules.Unlock()
+	}
+
+	return rep
+}
+
+// 记录rpc资源r的一次拒绝
+func (rep *Reporter) reject(r, reason string) {
+	key := reportRejection{time: time.Now().Truncate(time.Minute), resource: r, reason: reason}
+	rep.Lock()
+	rep.rejections[key]++
+	rep.Unlock()
+}
+
+// 记录一次规则变更
+func (rep *Reporter) ruleChange(entry AuditEntry) {
+	change := ReportRuleChange{
+		Time:        entry.Time,
+		FromVersion: entry.FromVersion,
+		ToVersion:   entry.ToVersion,
+		Rollback:    entry.Rollback,
+	}
+	for _, d := range entry.Diffs {
+		change.Resources = append(change.Resources, d.Resource)
+	}
+
+	rep.Lock()
+	rep.ruleChanges = append(rep.ruleChanges, change)
+	rep.Unlock()
+}
+
+// 采样所有rpc资源最近1分钟的p99，并清除超过保留时长的数据，需定时调用，见Run
+func (rep *Reporter) Sample() {
+	now := time.Now()
+	var samples []reportSample
+	rep.Breaker.windows.Range(func(k, _ interface{}) bool {
+		r := k.(string)
+		if p99 := rep.Breaker.P99(r, time.Minute); p99 > 0 {
+			samples = append(samples, reportSample{time: now, resource: r, p99: p99})
+		}
+		return true
+	})
+
+	rep.Lock()
+	defer rep.Unlock()
+	rep.samples = append(rep.samples, samples...)
+	rep.expire(now.Add(-rep.Retention))
+}
+
+// 清除before之前的数据，调用方需持有锁
+func (rep *Reporter) expire(before time.Time) {
+	i := sort.Search(len(rep.samples), func(i int) bool { return !rep.samples[i].time.Before(before) })
+	rep.samples = append(rep.samples[:0:0], rep.samples[i:]...)
+	i = sort.Search(len(rep.trips), func(i int) bool { return !rep.trips[i].trip.Time.Before(before) })
+	rep.trips = append(rep.trips[:0:0], rep.trips[i:]...)
+	i = sort.Search(len(rep.ruleChanges), func(i int) bool { return !rep.ruleChanges[i].Time.Before(before) })
+	rep.ruleChanges = append(rep.ruleChanges[:0:0], rep.ruleChanges[i:]...)
+	for k := range rep.rejections {
+		if k.time.Before(before) {
+			delete(rep.rejections, k)
+		}
+	}
+}
+
+// 生成[from, to)期间的报告，资源按熔断次数和拒绝次数降序排列
+func (rep *Reporter) Report(from, to time.Time) *Report {
+	rep.Lock()
+	defer rep.Unlock()
+
+	in := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+	resources := make(map[string]*ResourceReport)
+	get := func(r string) *ResourceReport {
+		rr, ok := resources[r]
+		if !ok {
+			rr = &ResourceReport{Resource: r, P99Trend: make([]time.Duration, reportTrendPoints)}
+			resources[r] = rr
+		}
+		return rr
+	}
+
+	for _, t := range rep.trips {
+		if in(t.trip.Time) {
+			rr := get(t.resource)
+			rr.Trips = append(rr.Trips, t.trip)
+		}
+	}
+
+	reasons := make(map[string]map[string]int64)
+	for k, n := range rep.rejections {
+		if !in(k.time) {
+			continue
+		}
+		get(k.resource).Rejections += n
+		if reasons[k.resource] == nil {
+			reasons[k.resource] = make(map[string]int64)
+		}
+		reasons[k.resource][k.reason] += n
+	}
+	for r, counts := range reasons {
+		rr := resources[r]
+		for reason, n := range counts {
+			rr.TopReasons = append(rr.TopReasons, ReportReason{Reason: reason, Count: n})
+		}
+		sort.Slice(rr.TopReasons, func(i, j int) bool {
+			a, b := rr.TopReasons[i], rr.TopReasons[j]
+			return a.Count > b.Count || (a.Count == b.Count && a.Reason < b.Reason)
+		})
+		if len(rr.TopReasons) > 5 {
+			rr.TopReasons = rr.TopReasons[:5]
+		}
+	}
+
+	slot := to.Sub(from) / reportTrendPoints
+	for _, s := range rep.samples {
+		if !in(s.time) || slot <= 0 {
+			continue
+		}
+		rr := get(s.resource)
+		i := int(s.time.Sub(from) / slot)
+		if i >= reportTrendPoints {
+			i = reportTrendPoints - 1
+		}
+		if s.p99 > rr.P99Trend[i] {
+			rr.P99Trend[i] = s.p99
+		}
+	}
+
+	report := &Report{From: from, To: to}
+	for _, rr := range resources {
+		report.Resources = append(report.Resources, *rr)
+	}
+	sort.Slice(report.Resources, func(i, j int) bool {
+		a, b := report.Resources[i], report.Resources[j]
+		if len(a.Trips) != len(b.Trips) {
+			return len(a.Trips) > len(b.Trips)
+		}
+		if a.Rejections != b.Rejections {
+			return a.Rejections > b.Rejections
+		}
+		return a.Resource < b.Resource
+	})
+	for _, c := range rep.ruleChanges {
+		if in(c.Time) {
+			report.RuleChanges = append(report.RuleChanges, c)
+		}
+	}
+
+	return report
+}
+
+// 生成截止到now的最近period的报告，如每小时或每天
+func (rep *Reporter) Last(period time.Duration, now time.Time) *Report {
+	return rep.Report(now.Add(-period), now)
+}
+
+// 报告中展示的资源名，ResourceKey编码的资源名展示为可读形式
+func reportResourceName(r string) string {
+	if strings.Contains(r, resourceKeySep) {
+		return DecodeResourceKey(r).String()
+	}
+
+	return r
+}
+
+// 渲染为Markdown
+func (report *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Governance report %s – %s\n\n", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339))
+
+	if len(report.Resources) == 0 {
+		b.WriteString("No trips, rejections or latency samples in this period.\n")
+	} else {
+		b.WriteString("| Resource | Trips | Rejections | Top reasons | p99 trend |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, rr := range report.Resources {
+			reasons := make([]string, 0, len(rr.TopReasons))
+			for _, r := range rr.TopReasons {
+				reasons = append(reasons, fmt.Sprintf("%s (%d)", r.Reason, r.Count))
+			}
+			trend := make([]string, 0, len(rr.P99Trend))
+			for _, p := range rr.P99Trend {
+				if p == 0 {
+					trend = append(trend, "-")
+				} else {
+					trend = append(trend, p.Round(time.Millisecond/10).String())
+				}
+			}
+			fmt.Fprintf(&b, "| %s | %d | %d | %s | %s |\n", reportResourceName(rr.Resource), len(rr.Trips), rr.Rejections,
+				strings.Join(reasons, ", "), strings.Join(trend, " → "))
+		}
+	}
+
+	if len(report.RuleChanges) > 0 {
+		b.WriteString("\n### Rule changes\n\n")
+		for _, c := range report.RuleChanges {
+			action := "applied"
+			if c.Rollback {
+				action = "rolled back"
+			}
+			fmt.Fprintf(&b, "- %s: v%d → v%d %s (%s)\n", c.Time.Format(time.RFC3339), c.FromVersion, c.ToVersion, action,
+				strings.Join(c.Resources, ", "))
+		}
+	}
+
+	return b.String()
+}
+
+// 返回最近period的报告，period参数如1h或24h，默认1h，format=markdown时返回Markdown，否则返回JSON
+func (rep *Reporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	period := time.Hour
+	if v := r.URL.Query().Get("period"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid period", http.StatusBadRequest)
+			return
+		}
+		period = d
+	}
+
+	report := rep.Last(period, time.Now())
+	if r.URL.Query().Get("format") == "markdown" {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(report.Markdown()))
+		return
+	}
+	writeJSON(w, report)
+}
+
+// 定时采样p99，ctx结束时返回
+func (rep *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rep.Sample()
+		}
+	}
+}
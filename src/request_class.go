@@ -0,0 +1,179 @@
+package governance
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// 请求类别各维度的取值
+const (
+	ClassRead      = "read"      // 只读请求
+	ClassWrite     = "write"     // 写请求
+	ClassCheap     = "cheap"     // 开销小的请求
+	ClassExpensive = "expensive" // 开销大的请求，如导出、报表、批量操作
+	ClassInternal  = "internal"  // 内部调用方发起的请求
+	ClassExternal  = "external"  // 外部用户发起的请求
+)
+
+// 标记内部调用方的请求头，也可作为gRPC metadata的key
+const InternalHeader = "x-governance-internal"
+
+// 请求类别，由读写、开销和来源三个维度组成
+type RequestClass struct {
+	Access string // read或write
+	Cost   string // cheap或expensive
+	Origin string // internal或external
+}
+
+// 请求所属的所有类别，按读写、开销、来源的顺序
+func (c RequestClass) Classes() []string {
+	classes := make([]string, 0, 3)
+	for _, v := range []string{c.Access, c.Cost, c.Origin} {
+		if v != "" {
+			classes = append(classes, v)
+		}
+	}
+
+	return classes
+}
+
+func (c RequestClass) String() string {
+	return strings.Join(c.Classes(), ",")
+}
+
+type requestClassKey struct{}
+
+// 为请求设置类别，下游的治理规则可按类别区分
+func WithRequestClass(ctx context.Context, c RequestClass) context.Context {
+	return context.WithValue(ctx, requestClassKey{}, c)
+}
+
+// 获取请求的类别
+func RequestClassFrom(ctx context.Context) (RequestClass, bool) {
+	c, ok := ctx.Value(requestClassKey{}).(RequestClass)
+	return c, ok
+}
+
+// 请求分类规则，所有条件都满足时将非空的维度覆盖到请求类别，条件为空表示不限制
+type ClassRule struct {
+	Method string `toml:"method"` // http方法，gRPC请求为空
+	Path   string `toml:"path"`   // http路径或gRPC方法全名的前缀
+	Header string `toml:"header"` // 需存在的请求头或gRPC metadata，格式为key或key=value
+	Access string `toml:"access"`
+	Cost   string `toml:"cost"`
+	Origin string `toml:"origin"`
+}
+
+// 按请求方法、路径和元数据划分请求类别的分类器
+// 默认按http方法或gRPC方法名判断读写，开销为cheap，携带InternalHeader的请求为internal，其余为external，之后按规则依次覆盖
+type RequestClassifier struct {
+	Rules []ClassRule `toml:"rules"`
+}
+
+// gRPC中按方法名前缀视为只读的方法
+var readMethodPrefixes = []string{"Get", "List", "Query", "Search", "Describe", "Check", "Watch"}
+
+// 对请求分类，method为http方法，gRPC请求为空，path为http路径或gRPC方法全名，get读取请求头或gRPC metadata
+func (c *RequestClassifier) Classify(method, path string, get func(key string) string) RequestClass {
+	class := RequestClass{Access: ClassWrite, Cost: ClassCheap, Origin: ClassExternal}
+	switch {
+	case method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions:
+		class.Access = ClassRead
+	case method == "":
+		name := path[strings.LastIndexByte(path, '/')+1:]
+		for _, prefix := range readMethodPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				class.Access = ClassRead
+				break
+			}
+		}
+	}
+	if get(InternalHeader) != "" {
+		class.Origin = ClassInternal
+	}
+
+	for _, rule := range c.Rules {
+		if !rule.match(method, path, get) {
+			continue
+		}
+		if rule.Access != "" {
+			class.Access = rule.Access
+		}
+		if rule.Cost != "" {
+			class.Cost = rule.Cost
+		}
+		if rule.Origin != "" {
+			class.Origin = rule.Origin
+		}
+	}
+
+	return class
+}
+
+func (rule ClassRule) match(method, path string, get func(key string) string) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, method) {
+		return false
+	}
+	if rule.Path != "" && !strings.HasPrefix(path, rule.Path) {
+		return false
+	}
+	if rule.Header != "" {
+		key, value, hasValue := strings.Cut(rule.Header, "=")
+		v := get(key)
+		if v == "" || (hasValue && v != value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// http中间件，为请求设置类别
+func ClassifyHandler(next http.Handler, c *RequestClassifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := c.Classify(r.Method, r.URL.Path, r.Header.Get)
+		next.ServeHTTP(w, r.WithContext(WithRequestClass(r.Context(), class)))
+	})
+}
+
+// 请求类别的治理策略，见GovernanceConfig.Classes
+type ClassPolicy struct {
+	Cost     float64  `toml:"cost"`     // 请求未声明代价时使用的代价
+	Priority string   `toml:"priority"` // 请求未设置优先级时使用的优先级，sheddable、normal或critical
+	Stages   []string `toml:"stages"`   // 关闭的处理阶段
+	// 是否按类别拆分rpc资源，拆分后该类别的请求使用ResourceKey.Extra为类别名的独立rpc资源，熔断和限流与其他类别互不影响
+	Split bool `toml:"split"`
+}
+
+// 按请求ctx的类别调整请求和rpc资源r，返回调整后的ctx、rpc资源名和关闭的处理阶段
+func (g *Governance) applyClass(ctx context.Context, r string) (context.Context, string, []string) {
+	class, ok := RequestClassFrom(ctx)
+	if !ok || len(g.Config.Classes) == 0 {
+		return ctx, r, nil
+	}
+
+	var disabled []string
+	for _, name := range class.Classes() {
+		policy, ok := g.Config.Classes[name]
+		if !ok || policy == nil {
+			continue
+		}
+		if _, ok := ctx.Value(costKey{}).(float64); !ok && policy.Cost > 0 {
+			ctx = WithCost(ctx, policy.Cost)
+		}
+		if _, ok := ctx.Value(priorityKey{}).(Priority); !ok && policy.Priority != "" {
+			if p := ParsePriority(func(string) string { return policy.Priority }); p != 0 {
+				ctx = WithPriority(ctx, p)
+			}
+		}
+		disabled = append(disabled, policy.Stages...)
+		if policy.Split {
+			k := DecodeResourceKey(r)
+			k.Extra = name
+			r = k.Encode()
+		}
+	}
+
+	return ctx, r, disabled
+}
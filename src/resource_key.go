@@ -0,0 +1,61 @@
+package governance
+
+import (
+	"strings"
+)
+
+// 资源标识各维度之间的分隔符
+const resourceKeySep = "\x1f"
+
+// 多维度的rpc资源标识
+type ResourceKey struct {
+	Service  string // 服务名
+	Method   string // 方法名
+	Instance string // 实例地址
+	Extra    string // 扩展维度，如租户
+}
+
+// 将资源标识编码为熔断器使用的rpc资源名
+func (k ResourceKey) Encode() string {
+	return k.Service + resourceKeySep + k.Method + resourceKeySep + k.Instance + resourceKeySep + k.Extra
+}
+
+// 可读的资源标识，用于日志和展示
+func (k ResourceKey) String() string {
+	s := k.Service + "/" + k.Method
+	if k.Instance != "" {
+		s += "@" + k.Instance
+	}
+	if k.Extra != "" {
+		s += "#" + k.Extra
+	}
+
+	return s
+}
+
+// 判断资源标识是否匹配pattern，pattern中为空的维度匹配任意值
+func (k ResourceKey) Match(pattern ResourceKey) bool {
+	return matchDimension(pattern.Service, k.Service) &&
+		matchDimension(pattern.Method, k.Method) &&
+		matchDimension(pattern.Instance, k.Instance) &&
+		matchDimension(pattern.Extra, k.Extra)
+}
+
+func matchDimension(pattern, v string) bool {
+	return pattern == "" || pattern == v
+}
+
+// 将rpc资源名解码为资源标识，非Encode生成的资源名整体作为服务名
+func DecodeResourceKey(r string) ResourceKey {
+	parts := strings.SplitN(r, resourceKeySep, 4)
+	if len(parts) != 4 {
+		return ResourceKey{Service: r}
+	}
+
+	return ResourceKey{
+		Service:  parts[0],
+		Method:   parts[1],
+		Instance: parts[2],
+		Extra:    parts[3],
+	}
+}
@@ -0,0 +1,100 @@
+package governance
+
+import (
+	"context"
+	"time"
+)
+
+// 重试配置
+type RetryConfig struct {
+	MaxAttempts    int   `toml:"max_attempts"`    // 最大尝试次数，包含首次调用，小于1时按1处理即不重试
+	Backoff        int64 `toml:"backoff"`         // 两次尝试之间的等待时间，单位毫秒
+	AttemptTimeout int64 `toml:"attempt_timeout"` // 单次尝试的超时时间，单位毫秒，为0时按剩余时间平分
+	Timeout        int64 `toml:"timeout"`         // 所有尝试的总超时时间，单位毫秒，为0时只受ctx限制
+}
+
+type noRetryKey struct{}
+
+// 声明请求不可重试，如请求体无法重新读取时，Governance.Execute只调用一次
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+// 请求是否声明了不可重试
+func retryDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noRetryKey{}).(bool)
+	return disabled
+}
+
+// 重试器
+type Retry struct {
+	Config     *RetryConfig
+	Classifier Classifier // 判断调用结果是否需要重试，为空时返回错误即重试
+}
+
+// 最大尝试次数，未配置时只调用一次
+func (config *RetryConfig) maxAttempts() int {
+	if config.MaxAttempts < 1 {
+		return 1
+	}
+
+	return config.MaxAttempts
+}
+
+// 创建重试器
+func NewRetry(config *RetryConfig) *Retry {
+	return &Retry{Config: config}
+}
+
+// 调用fn，失败时重试，每次尝试使用独立的超时时间，所有尝试受总超时时间限制
+func (rt *Retry) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if rt.Config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(rt.Config.Timeout)*time.Millisecond)
+		defer cancel()
+	}
+
+	classify := rt.Classifier
+	if classify == nil {
+		classify = DefaultClassifier
+	}
+
+	var err error
+	maxAttempts := rt.Config.maxAttempts()
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 && rt.Config.Backoff > 0 {
+			timer := time.NewTimer(time.Duration(rt.Config.Backoff) * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = rt.attempt(ctx, maxAttempts-attempt, fn)
+		if classify(err) != OutcomeFailure || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// 执行一次尝试，remaining为包含本次在内的剩余尝试次数
+func (rt *Retry) attempt(ctx context.Context, remaining int, fn func(ctx context.Context) error) error {
+	timeout := time.Duration(rt.Config.AttemptTimeout) * time.Millisecond
+	if timeout <= 0 {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return fn(ctx)
+		}
+		// 未配置单次超时时，将剩余时间平分给剩余的尝试
+		timeout = time.Until(deadline) / time.Duration(remaining)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return fn(attemptCtx)
+}
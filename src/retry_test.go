@@ -0,0 +1,34 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetryAttempts(t *testing.T) {
+	errFail := errors.New("fail")
+	tests := []struct {
+		maxAttempts int
+		want        int
+	}{
+		{0, 1},
+		{-1, 1},
+		{1, 1},
+		{3, 3},
+	}
+	for _, tt := range tests {
+		calls := 0
+		rt := NewRetry(&RetryConfig{MaxAttempts: tt.maxAttempts})
+		err := rt.Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return errFail
+		})
+		if err != errFail {
+			t.Errorf("MaxAttempts %d: err = %v, want %v", tt.maxAttempts, err, errFail)
+		}
+		if calls != tt.want {
+			t.Errorf("MaxAttempts %d: fn called %d times, want %d", tt.maxAttempts, calls, tt.want)
+		}
+	}
+}
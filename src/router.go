@@ -0,0 +1,159 @@
+package governance
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 服务版本权重的渐变过程
+type weightRamp struct {
+	from  map[string]int // 渐变开始时的权重
+	to    map[string]int // 目标权重
+	start time.Time      // 渐变开始时间
+}
+
+// 按版本权重路由的路由器，权重变更时在RampDuration内平滑过渡
+type Router struct {
+	sync.Mutex
+	RampDuration time.Duration // 权重渐变时长，为0时立即切换
+	routes       map[string]*weightRamp
+	experiments  map[string]*Experiment
+}
+
+// 实验分组函数，根据请求上下文返回请求所属的实验分组，返回false表示请求不参与实验
+type ExperimentAssigner func(ctx context.Context) (string, bool)
+
+// A/B实验路由规则
+type Experiment struct {
+	Assign   ExperimentAssigner
+	Versions map[string]string // 实验分组对应的服务版本
+}
+
+// 创建路由器
+func NewRouter(rampDuration time.Duration) *Router {
+	return &Router{
+		RampDuration: rampDuration,
+		routes:       make(map[string]*weightRamp),
+		experiments:  make(map[string]*Experiment),
+	}
+}
+
+// 设置服务service各版本的目标权重
+func (router *Router) SetWeights(service string, weights map[string]int) {
+	router.Lock()
+	defer router.Unlock()
+
+	now := time.Now()
+	from := map[string]int{}
+	if ramp, ok := router.routes[service]; ok {
+		from = router.current(ramp, now)
+	}
+
+	to := make(map[string]int, len(weights))
+	for version, w := range weights {
+		to[version] = w
+	}
+
+	router.routes[service] = &weightRamp{from: from, to: to, start: now}
+}
+
+// 获取服务service各版本当前生效的权重
+func (router *Router) Weights(service string) map[string]int {
+	router.Lock()
+	defer router.Unlock()
+
+	ramp, ok := router.routes[service]
+	if !ok {
+		return nil
+	}
+
+	current := router.current(ramp, time.Now())
+	weights := make(map[string]int, len(current))
+	for version, w := range current {
+		weights[version] = w
+	}
+
+	return weights
+}
+
+// 按当前权重为服务service选择版本
+func (router *Router) Pick(service string) (string, bool) {
+	weights := router.Weights(service)
+
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		return "", false
+	}
+
+	n := rand.Intn(total)
+	for version, w := range weights {
+		if n < w {
+			return version, true
+		}
+		n -= w
+	}
+
+	return "", false
+}
+
+// 设置服务service的A/B实验路由规则，exp为空时删除规则
+func (router *Router) SetExperiment(service string, exp *Experiment) {
+	router.Lock()
+	defer router.Unlock()
+
+	if exp == nil {
+		delete(router.experiments, service)
+		return
+	}
+	router.experiments[service] = exp
+}
+
+// 为请求ctx选择服务service的版本，优先使用调试覆盖指定的版本，参与实验的请求按实验分组路由，其余请求按权重路由
+func (router *Router) Route(ctx context.Context, service string) (string, bool) {
+	if o, ok := overridesFrom(ctx); ok && o.Route != "" {
+		return o.Route, true
+	}
+
+	router.Lock()
+	exp, ok := router.experiments[service]
+	router.Unlock()
+
+	if ok {
+		if group, ok := exp.Assign(ctx); ok {
+			if version, ok := exp.Versions[group]; ok {
+				return version, true
+			}
+		}
+	}
+
+	return router.Pick(service)
+}
+
+// 计算渐变过程在now时刻的权重
+func (router *Router) current(ramp *weightRamp, now time.Time) map[string]int {
+	elapsed := now.Sub(ramp.start)
+	if router.RampDuration <= 0 || elapsed >= router.RampDuration || len(ramp.from) == 0 {
+		return ramp.to
+	}
+
+	progress := float64(elapsed) / float64(router.RampDuration)
+	weights := make(map[string]int)
+	for version, w := range ramp.from {
+		weights[version] = w
+	}
+	for version := range ramp.to {
+		if _, ok := weights[version]; !ok {
+			weights[version] = 0
+		}
+	}
+	for version, from := range weights {
+		weights[version] = from + int(float64(ramp.to[version]-from)*progress)
+	}
+
+	return weights
+}
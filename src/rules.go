@@ -0,0 +1,168 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// 规则版本已存在
+	ErrRuleVersionExists = errors.New("governance: rule version already exists")
+	// 规则版本不存在
+	ErrRuleVersionNotFound = errors.New("governance: rule version not found")
+)
+
+// 治理规则集
+type RuleSet struct {
+	Breakers map[string]*Config `toml:"breakers"` // rpc资源的熔断配置
+}
+
+// 单个rpc资源的规则变更
+type RuleDiff struct {
+	Resource string  `json:"resource"`
+	Old      *Config `json:"old"` // 变更前的配置，为空表示新增
+	New      *Config `json:"new"` // 变更后的配置，为空表示删除
+}
+
+// 规则变更的审计记录
+type AuditEntry struct {
+	Time        time.Time
+	FromVersion int64
+	ToVersion   int64
+	Rollback    bool // 是否为回滚
+	Diffs       []RuleDiff
+}
+
+// 带版本的规则管理器
+type RuleManager struct {
+	Breaker  *Breaker
+	AuditLog func(entry AuditEntry) // 审计日志，为空时不记录
+	// 受保护模式，开启后新规则先进入待确认列表，经Confirm人工确认后才生效，回滚不受影响
+	Guarded bool
+
+	sync.Mutex
+	versions map[int64]*RuleSet
+	pending  map[int64]*RuleSet // 待确认的规则集
+	current  int64
+}
+
+// 创建规则管理器
+func NewRuleManager(breaker *Breaker) *RuleManager {
+	return &RuleManager{
+		Breaker:  breaker,
+		versions: make(map[int64]*RuleSet),
+		pending:  make(map[int64]*RuleSet),
+	}
+}
+
+// 应用版本为version的规则集，受保护模式下规则进入待确认列表并返回ErrConfirmationRequired
+func (m *RuleManager) ApplyRules(version int64, rules *RuleSet) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.versions[version]; ok {
+		return ErrRuleVersionExists
+	}
+	if m.Guarded {
+		m.pending[version] = rules
+		return ErrConfirmationRequired
+	}
+	m.versions[version] = rules
+	m.apply(version, false)
+
+	return nil
+}
+
+// 回滚到版本toVersion的规则集
+func (m *RuleManager) Rollback(toVersion int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.versions[toVersion]; !ok {
+		return ErrRuleVersionNotFound
+	}
+	m.apply(toVersion, true)
+
+	return nil
+}
+
+// 当前生效的规则版本
+func (m *RuleManager) CurrentVersion() int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.current
+}
+
+// 所有规则版本，按版本号升序
+func (m *RuleManager) Versions() []int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	versions := make([]int64, 0, len(m.versions))
+	for v := range m.versions {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	return versions
+}
+
+// 切换到版本version的规则集，调用方需持有锁
+func (m *RuleManager) apply(version int64, rollback bool) {
+	old := m.versions[m.current]
+	rules := m.versions[version]
+	diffs := diffRules(old, rules)
+	decision := "apply"
+	if rollback {
+		decision = "rollback"
+	}
+	for _, d := range diffs {
+		m.Breaker.SetConfig(d.Resource, d.New)
+		logDecision(context.Background(), LogRules, slog.LevelInfo, "rule changed", d.Resource, fmt.Sprintf("v%d", version), decision,
+			slog.Int64("from_version", m.current), slog.Bool("removed", d.New == nil))
+	}
+
+	if m.AuditLog != nil {
+		m.AuditLog(AuditEntry{
+			Time:        time.Now(),
+			FromVersion: m.current,
+			ToVersion:   version,
+			Rollback:    rollback,
+			Diffs:       diffs,
+		})
+	}
+	m.current = version
+}
+
+// 比较两个规则集的差异
+func diffRules(old, rules *RuleSet) []RuleDiff {
+	var oldBreakers, newBreakers map[string]*Config
+	if old != nil {
+		oldBreakers = old.Breakers
+	}
+	if rules != nil {
+		newBreakers = rules.Breakers
+	}
+
+	var diffs []RuleDiff
+	for r, c := range newBreakers {
+		if o, ok := oldBreakers[r]; !ok || !reflect.DeepEqual(o, c) {
+			diffs = append(diffs, RuleDiff{Resource: r, Old: o, New: c})
+		}
+	}
+	for r, o := range oldBreakers {
+		if _, ok := newBreakers[r]; !ok {
+			diffs = append(diffs, RuleDiff{Resource: r, Old: o})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Resource < diffs[j].Resource })
+
+	return diffs
+}
@@ -0,0 +1,159 @@
+package governance
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+)
+
+var (
+	// 受保护模式下规则需人工确认后才生效
+	ErrConfirmationRequired = errors.New("governance: rules pending manual confirmation")
+	// 没有待确认的规则版本
+	ErrNoPendingRules = errors.New("governance: no pending rules for version")
+)
+
+// 配置字段的变更
+type FieldChange struct {
+	Field string      `json:"field"` // 字段的toml名
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// 单个rpc资源生效配置的变更
+type EffectiveDiff struct {
+	Resource string        `json:"resource"`
+	Changes  []FieldChange `json:"changes"`
+}
+
+// 规则变更计划，包含规则本身的差异和按层级合并后各rpc资源生效配置的差异
+type RulePlan struct {
+	FromVersion int64           `json:"from_version"`
+	ToVersion   int64           `json:"to_version"`
+	Rules       []RuleDiff      `json:"rules"`
+	Effective   []EffectiveDiff `json:"effective"`
+}
+
+// 计算应用版本为version的规则集rules将产生的变更，不修改当前生效的规则
+func (m *RuleManager) Plan(version int64, rules *RuleSet) (*RulePlan, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.versions[version]; ok {
+		return nil, ErrRuleVersionExists
+	}
+
+	return m.plan(version, rules), nil
+}
+
+// 计算切换到规则集rules的变更计划，调用方需持有锁
+func (m *RuleManager) plan(version int64, rules *RuleSet) *RulePlan {
+	diffs := diffRules(m.versions[m.current], rules)
+	plan := &RulePlan{
+		FromVersion: m.current,
+		ToVersion:   version,
+		Rules:       diffs,
+	}
+
+	b := m.Breaker
+	b.RLock()
+	defer b.RUnlock()
+
+	// 在当前配置的副本上应用规则差异，得到变更后的各层级配置
+	next := make(map[string]*Config, len(b.Configs))
+	for r, c := range b.Configs {
+		next[r] = c
+	}
+	for _, d := range diffs {
+		if d.New == nil {
+			delete(next, d.Resource)
+		} else {
+			next[d.Resource] = d.New
+		}
+	}
+
+	// 受影响的rpc资源包括已有调用的资源和规则中直接配置的资源
+	resources := make(map[string]bool)
+	for r := range b.R {
+		resources[r] = true
+	}
+	for r := range b.stats {
+		resources[r] = true
+	}
+	for _, d := range diffs {
+		resources[d.Resource] = true
+	}
+
+	for r := range resources {
+		old, _ := resolveConfig(b.Config, b.Configs, b.tuned, r)
+		cur, _ := resolveConfig(b.Config, next, b.tuned, r)
+		if changes := diffConfig(old, cur); len(changes) > 0 {
+			plan.Effective = append(plan.Effective, EffectiveDiff{Resource: r, Changes: changes})
+		}
+	}
+	sort.Slice(plan.Effective, func(i, j int) bool { return plan.Effective[i].Resource < plan.Effective[j].Resource })
+
+	return plan
+}
+
+// 比较两个配置的字段差异
+func diffConfig(old, cur *Config) []FieldChange {
+	var changes []FieldChange
+	ov, cv := reflect.ValueOf(old).Elem(), reflect.ValueOf(cur).Elem()
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		o, c := ov.Field(i).Interface(), cv.Field(i).Interface()
+		if !reflect.DeepEqual(o, c) {
+			changes = append(changes, FieldChange{Field: configFieldName(t.Field(i)), Old: configFieldValue(ov.Field(i)), New: configFieldValue(cv.Field(i))})
+		}
+	}
+
+	return changes
+}
+
+// 待人工确认的规则变更计划，按版本号升序
+func (m *RuleManager) Pending() []*RulePlan {
+	m.Lock()
+	defer m.Unlock()
+
+	plans := make([]*RulePlan, 0, len(m.pending))
+	for version, rules := range m.pending {
+		plans = append(plans, m.plan(version, rules))
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].ToVersion < plans[j].ToVersion })
+
+	return plans
+}
+
+// 确认并应用待确认的规则版本version
+func (m *RuleManager) Confirm(version int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	rules, ok := m.pending[version]
+	if !ok {
+		return ErrNoPendingRules
+	}
+	// 版本已存在时保留待确认的规则，由调用方决定丢弃
+	if _, ok := m.versions[version]; ok {
+		return ErrRuleVersionExists
+	}
+	delete(m.pending, version)
+	m.versions[version] = rules
+	m.apply(version, false)
+
+	return nil
+}
+
+// 丢弃待确认的规则版本version
+func (m *RuleManager) Discard(version int64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.pending[version]; !ok {
+		return ErrNoPendingRules
+	}
+	delete(m.pending, version)
+
+	return nil
+}
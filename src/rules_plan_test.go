@@ -0,0 +1,31 @@
+package governance
+
+import "testing"
+
+// 确认的版本已存在时返回ErrRuleVersionExists，待确认的规则保留到被丢弃
+func TestConfirmExistingVersionKeepsPending(t *testing.T) {
+	config := DefaultConfig()
+	m := NewRuleManager(newBreaker(&config))
+	m.Guarded = true
+	rules := &RuleSet{Breakers: map[string]*Config{"a": {FailThreshold: 3}}}
+	if err := m.ApplyRules(1, rules); err != ErrConfirmationRequired {
+		t.Fatalf("ApplyRules = %v, want %v", err, ErrConfirmationRequired)
+	}
+
+	m.Guarded = false
+	if err := m.ApplyRules(1, rules); err != nil {
+		t.Fatalf("ApplyRules = %v", err)
+	}
+	if err := m.Confirm(1); err != ErrRuleVersionExists {
+		t.Fatalf("Confirm = %v, want %v", err, ErrRuleVersionExists)
+	}
+	if plans := m.Pending(); len(plans) != 1 || plans[0].ToVersion != 1 {
+		t.Fatalf("Pending = %+v, want version 1", plans)
+	}
+	if err := m.Discard(1); err != nil {
+		t.Fatalf("Discard = %v", err)
+	}
+	if err := m.Confirm(1); err != ErrNoPendingRules {
+		t.Fatalf("Confirm = %v, want %v", err, ErrNoPendingRules)
+	}
+}
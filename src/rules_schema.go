@@ -0,0 +1,455 @@
+package governance
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 规则集JSON Schema的标识
+const RuleSchemaID = "https://github.com/huago/service-governance/schema/rules.schema.json"
+
+// 规则校验错误
+type ValidationError struct {
+	Path    string `json:"path"` // 出错字段的路径，如breakers.user-service.fail_threshold
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// 数值字段的取值范围，未列出的数值字段最小值为0
+var configFieldRanges = map[string][2]float64{
+	"fail_threshold": {1, 0},
+	"succ_threshold": {1, 0},
+	"tick_jitter":    {0, 1},
+}
+
+// 时长字段的类型
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 曾以整数秒填写的时长字段，仍兼容不带单位的数值，已废弃；其他时长字段只接受字符串
+var legacyDurationFields = map[string]bool{
+	"open_timeout":     true,
+	"max_open_timeout": true,
+}
+
+// 熔断配置的JSON Schema，由Config的字段和toml名生成
+func configSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := configFieldName(f)
+		var prop map[string]interface{}
+		kind := f.Type.Kind()
+		if kind == reflect.Ptr {
+			kind = f.Type.Elem().Kind()
+		}
+		switch {
+		case f.Type == durationType && legacyDurationFields[name]:
+			// 时长写为"30s"、"2m"等字符串，整数按秒处理，已废弃
+			prop = map[string]interface{}{"type": []interface{}{"string", "integer"}, "format": "duration", "minimum": 0}
+		case f.Type == durationType:
+			prop = map[string]interface{}{"type": "string", "format": "duration"}
+		case kind == reflect.Bool:
+			prop = map[string]interface{}{"type": "boolean"}
+		case kind == reflect.Int || kind == reflect.Int64:
+			prop = map[string]interface{}{"type": "integer", "minimum": 0}
+		case kind == reflect.Float64:
+			prop = map[string]interface{}{"type": "number", "minimum": 0}
+		case kind == reflect.Map && f.Type.Elem().Kind() == reflect.Float64:
+			prop = map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "number", "minimum": 0}}
+		case kind == reflect.Map:
+			prop = map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}}
+		default:
+			continue
+		}
+		if r, ok := configFieldRanges[name]; ok {
+			prop["minimum"] = r[0]
+			if r[1] > r[0] {
+				prop["maximum"] = r[1]
+			}
+		}
+		properties[name] = prop
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// 规则集的JSON Schema，可发布给配置平台或由govctl validate使用
+func RuleSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     RuleSchemaID,
+		"title":   "governance rules",
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"breakers": map[string]interface{}{
+				"type":                 "object",
+				"description":          "rpc资源名、服务名或部分维度的资源标识对应的熔断配置",
+				"additionalProperties": configSchema(),
+			},
+		},
+		"additionalProperties": false,
+	}
+}
+
+// 校验JSON格式的规则集，通过时返回解析后的规则集
+// 除字段名和取值范围外，还检查字段之间无效的组合
+func ValidateRules(data []byte) (*RuleSet, []ValidationError) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, []ValidationError{{Path: "$", Message: err.Error()}}
+	}
+
+	return validateRulesDocument(doc)
+}
+
+// 校验YAML格式的规则集，字段与JSON格式相同，见ValidateRules
+func ValidateRulesYAML(data []byte) (*RuleSet, []ValidationError) {
+	doc, err := decodeYAML(data)
+	if err != nil {
+		return nil, []ValidationError{{Path: "$", Message: err.Error()}}
+	}
+
+	return validateRulesDocument(doc)
+}
+
+// 校验解码后的规则集
+func validateRulesDocument(doc interface{}) (*RuleSet, []ValidationError) {
+	var errs []ValidationError
+	validateSchema("", RuleSchema(), doc, &errs)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	rules := &RuleSet{Breakers: make(map[string]*Config)}
+	if breakers, ok := doc.(map[string]interface{})["breakers"].(map[string]interface{}); ok {
+		for r, v := range breakers {
+			config := decodeConfig(v.(map[string]interface{}))
+			errs = append(errs, validateConfig("breakers."+r, config)...)
+			rules.Breakers[r] = config
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return rules, nil
+}
+
+// 检查熔断配置字段之间无效的组合
+func validateConfig(path string, config *Config) []ValidationError {
+	var errs []ValidationError
+	if timeout, max := config.openTimeouts(); max > 0 && max < timeout {
+		errs = append(errs, ValidationError{Path: path + ".max_open_timeout", Message: "must not be less than open_timeout"})
+	}
+	if config.disabled() && config.DryRun {
+		errs = append(errs, ValidationError{Path: path + ".dry_run", Message: "has no effect when disabled is true"})
+	}
+	if config.TickJitter > 0 && config.TickInterval == 0 {
+		errs = append(errs, ValidationError{Path: path + ".tick_jitter", Message: "requires tick_interval"})
+	}
+
+	return errs
+}
+
+// 按toml名解码JSON格式的熔断配置，时长写为"30s"、"2m"等字符串，不带单位的数值按秒处理，已废弃
+func (config *Config) UnmarshalJSON(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	return config.decode(doc)
+}
+
+// 按toml名编码熔断配置，时长编码为"30s"等字符串，零值字段表示继承低层级的配置，编码时省略
+func (config Config) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{})
+	v := reflect.ValueOf(config)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		if f.IsZero() {
+			continue
+		}
+		m[configFieldName(t.Field(i))] = configFieldValue(f)
+	}
+
+	return json.Marshal(m)
+}
+
+// 实现toml解码器（如github.com/BurntSushi/toml）的Unmarshaler接口，按与JSON相同的规则解码，不带单位的整数时长按秒处理
+func (config *Config) UnmarshalTOML(data interface{}) error {
+	return config.decode(normalizeTOML(data))
+}
+
+// 按schema校验解码后的对象并转换为熔断配置
+func (config *Config) decode(doc interface{}) error {
+	var errs []ValidationError
+	validateSchema("", configSchema(), doc, &errs)
+	if len(errs) > 0 {
+		return fmt.Errorf("governance: invalid config: %v", errs[0])
+	}
+	*config = *decodeConfig(doc.(map[string]interface{}))
+
+	return nil
+}
+
+// 将toml解码器产生的整数转换为float64，与encoding/json解码到interface{}的结果一致
+func normalizeTOML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = normalizeTOML(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = normalizeTOML(e)
+		}
+		return s
+	}
+
+	return v
+}
+
+// 配置字段用于展示的值，时长转换为"30s"等字符串
+func configFieldValue(f reflect.Value) interface{} {
+	if f.Type() == durationType {
+		return time.Duration(f.Int()).String()
+	}
+
+	return f.Interface()
+}
+
+// 按toml名将已通过schema校验的对象转换为熔断配置
+func decodeConfig(m map[string]interface{}) *Config {
+	config := &Config{}
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		raw, ok := m[configFieldName(t.Field(i))]
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			f.Set(reflect.New(f.Type().Elem()))
+			f = f.Elem()
+		}
+		if f.Type() == durationType {
+			f.SetInt(int64(decodeDuration(raw)))
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Bool:
+			f.SetBool(raw.(bool))
+		case reflect.Int, reflect.Int64:
+			f.SetInt(int64(raw.(float64)))
+		case reflect.Float64:
+			f.SetFloat(raw.(float64))
+		case reflect.Map:
+			if f.Type().Elem().Kind() == reflect.Float64 {
+				weights := make(map[string]float64)
+				for k, wv := range raw.(map[string]interface{}) {
+					weights[k] = wv.(float64)
+				}
+				f.Set(reflect.ValueOf(weights))
+				continue
+			}
+			tags := make(map[string]string)
+			for k, tv := range raw.(map[string]interface{}) {
+				tags[k] = tv.(string)
+			}
+			f.Set(reflect.ValueOf(tags))
+		}
+	}
+
+	return config
+}
+
+// 解码已通过schema校验的时长，字符串按time.ParseDuration解析，数值按秒处理，schema只允许legacyDurationFields使用数值
+func decodeDuration(raw interface{}) time.Duration {
+	if s, ok := raw.(string); ok {
+		d, _ := time.ParseDuration(s)
+		return d
+	}
+
+	return time.Duration(raw.(float64) * float64(time.Second))
+}
+
+// 按schema校验json解码后的值，支持type、properties、additionalProperties、minimum、maximum和duration格式
+// type为数组时按值的json类型选择其中一种校验
+func validateSchema(path string, schema map[string]interface{}, v interface{}, errs *[]ValidationError) {
+	at := path
+	if at == "" {
+		at = "$"
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: at, Message: fmt.Sprintf(format, args...)})
+	}
+
+	typ := schema["type"]
+	if types, ok := typ.([]interface{}); ok {
+		if typ = matchSchemaType(types, v); typ == nil {
+			fail("expected one of %v", types)
+			return
+		}
+	}
+	switch typ {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("expected object")
+			return
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			if p, ok := properties[k]; ok {
+				validateSchema(child, p.(map[string]interface{}), obj[k], errs)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case map[string]interface{}:
+				validateSchema(child, extra, obj[k], errs)
+			case bool:
+				if !extra {
+					msg := "unknown field"
+					if s := suggestField(k, properties); s != "" {
+						msg += fmt.Sprintf(", did you mean %q?", s)
+					}
+					*errs = append(*errs, ValidationError{Path: child, Message: msg})
+				}
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok || (typ == "integer" && n != float64(int64(n))) {
+			fail("expected %s", typ)
+			return
+		}
+		if min, ok := schemaNumber(schema["minimum"]); ok && n < min {
+			fail("must be >= %v", min)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && n > max {
+			fail("must be <= %v", max)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected boolean")
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			fail("expected string")
+			return
+		}
+		if schema["format"] == "duration" {
+			if d, err := time.ParseDuration(s); err != nil {
+				fail("invalid duration %q, use a value like \"30s\" or \"2m\"", s)
+			} else if d < 0 {
+				fail("must not be negative")
+			}
+		}
+	}
+}
+
+// 从types中选出与值的json类型匹配的类型，都不匹配时返回空
+func matchSchemaType(types []interface{}, v interface{}) interface{} {
+	for _, t := range types {
+		switch v.(type) {
+		case string:
+			if t == "string" {
+				return t
+			}
+		case float64:
+			if t == "integer" || t == "number" {
+				return t
+			}
+		case bool:
+			if t == "boolean" {
+				return t
+			}
+		}
+	}
+
+	return nil
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+// 为拼写错误的字段名推荐最接近的已知字段
+func suggestField(name string, properties map[string]interface{}) string {
+	best, bestDist := "", len(name)/2+1
+	for p := range properties {
+		if d := editDistance(strings.ToLower(name), p); d < bestDist || (d == bestDist && p < best) {
+			best, bestDist = p, d
+		}
+	}
+
+	return best
+}
+
+// 两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
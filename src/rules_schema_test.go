@@ -0,0 +1,69 @@
+package governance
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// 时长在JSON中写为字符串，不带单位的数值只在解码时按秒处理
+func TestConfigJSON(t *testing.T) {
+	var config Config
+	if err := json.Unmarshal([]byte(`{"open_timeout":"30s","max_open_timeout":120,"fail_threshold":3}`), &config); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if config.OpenTimeout != 30*time.Second || config.MaxOpenTimeout != 2*time.Minute || config.FailThreshold != 3 {
+		t.Fatalf("config = %+v", config)
+	}
+	if err := json.Unmarshal([]byte(`{"open_timeout":"abc"}`), &config); err == nil {
+		t.Fatal("Unmarshal accepted an invalid duration")
+	}
+
+	data, err := json.Marshal(Config{OpenTimeout: 500 * time.Microsecond, FailThreshold: 3, Disabled: Bool(false)})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `{"disabled":false,"fail_threshold":3,"open_timeout":"500µs"}`; string(data) != want {
+		t.Fatalf("Marshal = %s, want %s", data, want)
+	}
+	var decoded Config
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if timeout, _ := decoded.openTimeouts(); timeout != 500*time.Microsecond {
+		t.Fatalf("open timeout = %v, want 500µs", timeout)
+	}
+}
+
+// toml解码器产生的整数与JSON的数值按相同规则解码
+func TestConfigUnmarshalTOML(t *testing.T) {
+	var config Config
+	err := config.UnmarshalTOML(map[string]interface{}{
+		"open_timeout":   int64(30),
+		"fail_threshold": int64(5),
+		"tags":           map[string]interface{}{"team": "core"},
+	})
+	if err != nil {
+		t.Fatalf("UnmarshalTOML: %v", err)
+	}
+	if config.OpenTimeout != 30*time.Second || config.FailThreshold != 5 || config.Tags["team"] != "core" {
+		t.Fatalf("config = %+v", config)
+	}
+}
+
+// 新增的时长字段只接受字符串
+func TestDurationFieldsRequireStrings(t *testing.T) {
+	for _, field := range []string{"recovery_grace", "stat_window"} {
+		if _, errs := ValidateRules([]byte(`{"breakers":{"svc":{"` + field + `":5}}}`)); len(errs) != 1 || errs[0].Message != "expected string" {
+			t.Errorf("%s: errs = %v, want expected string", field, errs)
+		}
+	}
+
+	rules, errs := ValidateRules([]byte(`{"breakers":{"svc":{"recovery_grace":"500us"}}}`))
+	if len(errs) > 0 {
+		t.Fatalf("ValidateRules: %v", errs)
+	}
+	if grace := rules.Breakers["svc"].RecoveryGrace; grace != 500*time.Microsecond {
+		t.Fatalf("recovery grace = %v, want 500µs", grace)
+	}
+}
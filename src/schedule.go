@@ -0,0 +1,177 @@
+package governance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 规则生效的时间段，按本地时间每天重复，End早于Start时表示跨天
+// 设置Cron时改为在cron表达式每次触发后的Duration内生效，忽略Start、End和Weekdays
+type TimeWindow struct {
+	Start    string         `toml:"start"`    // 开始时间，格式为15:04
+	End      string         `toml:"end"`      // 结束时间，格式为15:04
+	Weekdays []time.Weekday `toml:"weekdays"` // 生效的星期，为空时每天生效
+	Cron     string         `toml:"cron"`     // 生效开始时间的cron表达式，格式为"分 时 日 月 星期"，如"0 2 * * 1-5"
+	Duration time.Duration  `toml:"duration"` // cron每次触发后的生效时长，为0时为1分钟
+}
+
+// 检查时间段的格式
+func (w TimeWindow) Validate() error {
+	if w.Cron != "" {
+		_, err := parseCron(w.Cron)
+		return err
+	}
+	if _, err := time.Parse("15:04", w.Start); err != nil {
+		return fmt.Errorf("governance: invalid window start %q", w.Start)
+	}
+	if _, err := time.Parse("15:04", w.End); err != nil {
+		return fmt.Errorf("governance: invalid window end %q", w.End)
+	}
+
+	return nil
+}
+
+// 判断时间t是否在时间段内
+func (w TimeWindow) Contains(t time.Time) bool {
+	if w.Cron != "" {
+		return w.containsCron(t)
+	}
+
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return false
+	}
+
+	if len(w.Weekdays) > 0 {
+		matched := false
+		for _, d := range w.Weekdays {
+			if d == t.Weekday() {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+	if startMinute <= endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+
+	return minute >= startMinute || minute < endMinute
+}
+
+// 判断时间t是否在cron某次触发后的生效时长内，从t所在的分钟向前逐分钟查找触发时间
+func (w TimeWindow) containsCron(t time.Time) bool {
+	c, err := parseCron(w.Cron)
+	if err != nil {
+		return false
+	}
+	d := w.Duration
+	if d <= 0 {
+		d = time.Minute
+	}
+
+	for m := t.Truncate(time.Minute); t.Sub(m) < d; m = m.Add(-time.Minute) {
+		if c.match(m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 定时规则，在时间段内替换rpc资源的熔断配置和限流配置
+type ScheduledRule struct {
+	Resource string         `toml:"resource"` // rpc资源名
+	Window   TimeWindow     `toml:"window"`   // 生效时间段
+	Breaker  *Config        `toml:"breaker"`  // 生效期间的熔断配置，为空时不替换
+	Limiter  *LimiterConfig `toml:"limiter"`  // 生效期间的限流配置，为空时不替换
+}
+
+// 定时规则的生效状态
+type scheduledState struct {
+	active  bool
+	breaker *Config        // 生效前的熔断配置
+	limiter *LimiterConfig // 生效前的限流配置
+}
+
+// 定时规则调度器
+type RuleScheduler struct {
+	Breaker *Breaker
+	Limiter *Limiter
+	Rules   []*ScheduledRule
+
+	sync.Mutex
+	states map[*ScheduledRule]*scheduledState
+}
+
+// 创建定时规则调度器
+func NewRuleScheduler(breaker *Breaker, limiter *Limiter, rules []*ScheduledRule) *RuleScheduler {
+	return &RuleScheduler{
+		Breaker: breaker,
+		Limiter: limiter,
+		Rules:   rules,
+		states:  make(map[*ScheduledRule]*scheduledState),
+	}
+}
+
+// 按时间now生效或撤销定时规则
+func (s *RuleScheduler) Evaluate(now time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, rule := range s.Rules {
+		state, ok := s.states[rule]
+		if !ok {
+			state = &scheduledState{}
+			s.states[rule] = state
+		}
+
+		active := rule.Window.Contains(now)
+		if active && !state.active {
+			if rule.Breaker != nil && s.Breaker != nil {
+				state.breaker = s.Breaker.GetConfig(rule.Resource)
+				s.Breaker.SetConfig(rule.Resource, rule.Breaker)
+			}
+			if rule.Limiter != nil && s.Limiter != nil {
+				state.limiter = s.Limiter.GetConfig()
+				s.Limiter.SetConfig(rule.Limiter)
+			}
+		} else if !active && state.active {
+			if rule.Breaker != nil && s.Breaker != nil {
+				s.Breaker.SetConfig(rule.Resource, state.breaker)
+			}
+			if rule.Limiter != nil && s.Limiter != nil {
+				s.Limiter.SetConfig(state.limiter)
+			}
+		}
+		state.active = active
+	}
+}
+
+// 定时评估规则，ctx结束时返回
+func (s *RuleScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.Evaluate(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Evaluate(time.Now())
+		}
+	}
+}
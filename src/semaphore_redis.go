@@ -0,0 +1,187 @@
+package governance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// 分布式信号量依赖的Redis脚本执行，可由go-redis等客户端的Eval适配实现，脚本返回整数
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// 获取许可：清除过期的持有者，持有者数小于上限时加入，时间使用Redis服务端时间避免实例间时钟偏差
+const semaphoreAcquireScript = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1`
+
+// 续约许可：持有者仍在集合中时延长过期时间
+const semaphoreRenewScript = `
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	return 0
+end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[1]), ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return 1`
+
+// 释放许可
+const semaphoreReleaseScript = `return redis.call('ZREM', KEYS[1], ARGV[1])`
+
+// 基于Redis有序集合的分布式信号量，在所有实例间限制对脆弱依赖的总并发数
+// 每个许可带有租约，持有者崩溃后许可在租约到期时自动回收
+type DistributedSemaphore struct {
+	Client      RedisScripter
+	Key         string        // 持有者集合的key
+	Limit       int64         // 所有实例合计的最大并发数
+	Lease       time.Duration // 许可的租约时长，执行期间每隔Lease/3续约，不小于1毫秒
+	WaitTimeout time.Duration // 没有空闲许可时等待的时间，0表示立即拒绝
+	Interval    time.Duration // 等待期间重试获取的间隔，默认50毫秒
+}
+
+// 检查租约时长，Redis按毫秒设置过期时间，不足1毫秒的租约无法设置
+func checkLease(lease time.Duration) error {
+	if lease < time.Millisecond {
+		return fmt.Errorf("governance: invalid semaphore lease %v: must be at least 1ms", lease)
+	}
+
+	return nil
+}
+
+// 创建分布式信号量，lease小于1毫秒时返回错误
+func NewDistributedSemaphore(client RedisScripter, key string, limit int64, lease time.Duration) (*DistributedSemaphore, error) {
+	if err := checkLease(lease); err != nil {
+		return nil, err
+	}
+
+	return &DistributedSemaphore{
+		Client:   client,
+		Key:      key,
+		Limit:    limit,
+		Lease:    lease,
+		Interval: 50 * time.Millisecond,
+	}, nil
+}
+
+// 分布式信号量的许可
+type SemaphoreLease struct {
+	sem    *DistributedSemaphore
+	holder string
+}
+
+// 执行脚本并返回整数结果
+func (s *DistributedSemaphore) eval(ctx context.Context, script string, args ...interface{}) (int64, error) {
+	v, err := s.Client.Eval(ctx, script, []string{s.Key}, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("governance: unexpected semaphore script result %v", v)
+	}
+
+	return n, nil
+}
+
+// 尝试获取一个许可，没有空闲许可时返回false
+func (s *DistributedSemaphore) TryAcquire(ctx context.Context) (*SemaphoreLease, bool, error) {
+	if err := checkLease(s.Lease); err != nil {
+		return nil, false, err
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, false, err
+	}
+	holder := hex.EncodeToString(buf)
+
+	n, err := s.eval(ctx, semaphoreAcquireScript, s.Limit, s.Lease.Milliseconds(), holder)
+	if err != nil || n == 0 {
+		return nil, false, err
+	}
+
+	return &SemaphoreLease{sem: s, holder: holder}, true, nil
+}
+
+// 获取一个许可，没有空闲许可时在WaitTimeout内重试，超时返回ErrIsolationRejected
+func (s *DistributedSemaphore) Acquire(ctx context.Context) (*SemaphoreLease, error) {
+	deadline := time.Now().Add(s.WaitTimeout)
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 50 * time.Millisecond
+	}
+
+	for {
+		lease, ok, err := s.TryAcquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return lease, nil
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			return nil, ErrIsolationRejected
+		}
+
+		timer := time.NewTimer(jitter(interval, 0.2))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// 续约许可，许可已过期被回收时返回false
+func (l *SemaphoreLease) Renew(ctx context.Context) (bool, error) {
+	n, err := l.sem.eval(ctx, semaphoreRenewScript, l.sem.Lease.Milliseconds(), l.holder)
+	return n == 1, err
+}
+
+// 释放许可
+func (l *SemaphoreLease) Release(ctx context.Context) error {
+	_, err := l.sem.eval(ctx, semaphoreReleaseScript, l.holder)
+	return err
+}
+
+// 持有一个许可调用fn，执行期间定时续约，Redis不可用时返回错误，由调用方决定是否降级
+func (s *DistributedSemaphore) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	lease, err := s.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				// 许可已被回收时停止续约，调用继续执行
+				if ok, err := lease.Renew(context.Background()); err == nil && !ok {
+					return
+				}
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		lease.Release(context.Background())
+	}()
+
+	return fn(ctx)
+}
@@ -0,0 +1,35 @@
+package governance
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type noScripter struct{}
+
+func (noScripter) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return int64(1), nil
+}
+
+// 不足1毫秒的租约在创建和使用时返回错误，而不是在续约时panic
+func TestDistributedSemaphoreLease(t *testing.T) {
+	for _, lease := range []time.Duration{0, 2 * time.Nanosecond, time.Microsecond} {
+		if _, err := NewDistributedSemaphore(noScripter{}, "sem", 1, lease); err == nil {
+			t.Errorf("lease %v accepted", lease)
+		}
+	}
+
+	s := &DistributedSemaphore{Client: noScripter{}, Key: "sem", Limit: 1}
+	if err := s.Execute(context.Background(), func(context.Context) error { return nil }); err == nil {
+		t.Fatal("Execute with zero lease succeeded")
+	}
+
+	s, err := NewDistributedSemaphore(noScripter{}, "sem", 1, time.Second)
+	if err != nil {
+		t.Fatalf("NewDistributedSemaphore: %v", err)
+	}
+	if err := s.Execute(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+}
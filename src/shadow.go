@@ -0,0 +1,183 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// 熔断策略，用于以影子模式与当前熔断器对比决策
+type BreakerStrategy interface {
+	// 判断rpc资源r当前是否允许调用
+	Allow(r string) bool
+	// 记录rpc资源r一次调用的结果和耗时
+	Record(r string, outcome Outcome, latency time.Duration)
+}
+
+// 影子策略与当前熔断器的决策对比统计
+type ShadowStats struct {
+	Agree         int64     `json:"agree"`          // 决策一致的次数
+	ActiveReject  int64     `json:"active_reject"`  // 仅当前熔断器拒绝的次数
+	ShadowReject  int64     `json:"shadow_reject"`  // 仅影子策略拒绝的次数
+	LastDiverged  time.Time `json:"last_diverged"`  // 最近一次决策不一致的时间
+	ShadowOutcome int64     `json:"shadow_outcome"` // 影子策略记录的调用结果数
+}
+
+// 挂载在rpc资源上的影子策略
+type shadow struct {
+	strategy BreakerStrategy
+	sync.Mutex
+	stats ShadowStats
+}
+
+// 为rpc资源r挂载影子策略，影子策略只记录与当前熔断器决策不一致的情况而不影响调用，strategy为空时移除
+// 当前熔断器拒绝的调用不会执行，影子策略也就收不到这些调用的结果
+func (breaker *Breaker) SetShadow(r string, strategy BreakerStrategy) {
+	if strategy == nil {
+		breaker.shadows.Delete(r)
+		return
+	}
+	breaker.shadows.Store(r, &shadow{strategy: strategy})
+}
+
+// 所有挂载了影子策略的rpc资源的对比统计
+func (breaker *Breaker) ShadowStats() map[string]ShadowStats {
+	stats := make(map[string]ShadowStats)
+	breaker.shadows.Range(func(k, v interface{}) bool {
+		s := v.(*shadow)
+		s.Lock()
+		stats[k.(string)] = s.stats
+		s.Unlock()
+		return true
+	})
+
+	return stats
+}
+
+// 对比影子策略对rpc资源r的决策，allowed为当前熔断器的决策
+func (breaker *Breaker) compareShadow(ctx context.Context, r string, allowed bool) {
+	v, ok := breaker.shadows.Load(r)
+	if !ok {
+		return
+	}
+	s := v.(*shadow)
+	shadowAllowed := s.strategy.Allow(r)
+
+	s.Lock()
+	switch {
+	case allowed == shadowAllowed:
+		s.stats.Agree++
+	case allowed:
+		s.stats.ShadowReject++
+		s.stats.LastDiverged = breaker.now()
+	default:
+		s.stats.ActiveReject++
+		s.stats.LastDiverged = breaker.now()
+	}
+	s.Unlock()
+
+	if allowed != shadowAllowed {
+		logDecision(ctx, LogBreaker, slog.LevelInfo, "shadow strategy diverged", r, statusName(breaker.getStatus(r)), "shadow",
+			slog.Bool("active_allowed", allowed), slog.Bool("shadow_allowed", shadowAllowed))
+	}
+}
+
+// 将rpc资源r的调用结果记录到影子策略
+func (breaker *Breaker) recordShadow(r string, outcome Outcome, latency time.Duration) {
+	v, ok := breaker.shadows.Load(r)
+	if !ok {
+		return
+	}
+	s := v.(*shadow)
+	s.strategy.Record(r, outcome, latency)
+
+	s.Lock()
+	s.stats.ShadowOutcome++
+	s.Unlock()
+}
+
+// 以另一个熔断器作为影子策略，通常为使用不同配置的InitLazyBreaker
+type BreakerAsStrategy struct {
+	Breaker *Breaker
+}
+
+func (s BreakerAsStrategy) Allow(r string) bool {
+	return s.Breaker.Allow(r)
+}
+
+func (s BreakerAsStrategy) Record(r string, outcome Outcome, latency time.Duration) {
+	switch outcome {
+	case OutcomeFailure:
+		s.Breaker.setFail(r, errShadowFailure)
+	case OutcomeSuccess:
+		s.Breaker.setSucc(r)
+	}
+}
+
+// 影子策略记录失败时使用的错误
+var errShadowFailure = errors.New("governance: failure recorded by shadow strategy")
+
+// 按错误率熔断的策略：Window内请求数不少于MinRequests且错误率达到MaxErrorRate时打开OpenTimeout，
+// 到期后清空统计重新判断，可作为影子策略验证由计数熔断迁移到错误率熔断的效果
+type ErrorRateStrategy struct {
+	Window       time.Duration // 统计窗口，最长1分钟
+	MinRequests  int64
+	MaxErrorRate float64
+	OpenTimeout  time.Duration
+
+	sync.Mutex
+	windows   map[string]*windowStats
+	openUntil map[string]time.Time
+}
+
+// 创建按错误率熔断的策略
+func NewErrorRateStrategy(window time.Duration, minRequests int64, maxErrorRate float64, openTimeout time.Duration) *ErrorRateStrategy {
+	return &ErrorRateStrategy{
+		Window:       window,
+		MinRequests:  minRequests,
+		MaxErrorRate: maxErrorRate,
+		OpenTimeout:  openTimeout,
+		windows:      make(map[string]*windowStats),
+		openUntil:    make(map[string]time.Time),
+	}
+}
+
+func (s *ErrorRateStrategy) Allow(r string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now()
+	if now.Before(s.openUntil[r]) {
+		return false
+	}
+	w, ok := s.windows[r]
+	if !ok {
+		return true
+	}
+	sum := w.sum(now, s.Window)
+	if sum.requests < s.MinRequests || float64(sum.failures) < s.MaxErrorRate*float64(sum.requests) {
+		return true
+	}
+	s.openUntil[r] = now.Add(s.OpenTimeout)
+	s.windows[r] = &windowStats{}
+
+	return false
+}
+
+func (s *ErrorRateStrategy) Record(r string, outcome Outcome, latency time.Duration) {
+	if outcome == OutcomeIgnore {
+		return
+	}
+
+	s.Lock()
+	w, ok := s.windows[r]
+	if !ok {
+		w = &windowStats{}
+		s.windows[r] = w
+	}
+	s.Unlock()
+
+	w.record(time.Now(), latency, outcome == OutcomeFailure)
+}
@@ -0,0 +1,82 @@
+package governance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"time"
+)
+
+// 模拟负载的一个阶段，在[At, At+Duration)内以固定QPS调用rpc资源Resource
+type SimulationPhase struct {
+	Resource      string  `json:"resource"`
+	At            int64   `json:"at_ms"`             // 阶段开始时间相对模拟开始的偏移，单位毫秒
+	Duration      int64   `json:"duration_ms"`       // 阶段持续时间，单位毫秒
+	QPS           float64 `json:"qps"`               // 每秒调用数
+	FailureRate   float64 `json:"failure_rate"`      // 调用失败的概率，0到1
+	Latency       int64   `json:"latency_ms"`        // 调用耗时，单位毫秒
+	LatencyJitter int64   `json:"latency_jitter_ms"` // 调用耗时的随机增量上限，单位毫秒
+	Error         string  `json:"error"`             // 失败调用返回的错误，为空时为simulated failure
+}
+
+// 模拟负载配置，同一资源的多个阶段可以重叠
+type SimulationProfile struct {
+	Seed   int64             `json:"seed"` // 随机数种子，相同种子生成相同的调用序列
+	Phases []SimulationPhase `json:"phases"`
+}
+
+// 从JSON中读取模拟负载配置
+func LoadSimulationProfile(r io.Reader) (*SimulationProfile, error) {
+	var p SimulationProfile
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+	for i, phase := range p.Phases {
+		switch {
+		case phase.Resource == "":
+			return nil, fmt.Errorf("governance: simulation phase %d has no resource", i)
+		case phase.Duration <= 0 || phase.QPS <= 0:
+			return nil, fmt.Errorf("governance: simulation phase %d must have positive duration_ms and qps", i)
+		case phase.FailureRate < 0 || phase.FailureRate > 1:
+			return nil, fmt.Errorf("governance: simulation phase %d failure_rate must be within [0, 1]", i)
+		case phase.Latency < 0 || phase.LatencyJitter < 0:
+			return nil, fmt.Errorf("governance: simulation phase %d latency must not be negative", i)
+		}
+	}
+
+	return &p, nil
+}
+
+// 按配置生成从start开始的合成调用，每个阶段内的调用均匀分布
+func (p *SimulationProfile) Records(start time.Time) []ReplayRecord {
+	rnd := rand.New(rand.NewSource(p.Seed))
+	var records []ReplayRecord
+	for _, phase := range p.Phases {
+		interval := time.Duration(float64(time.Second) / phase.QPS)
+		at := start.Add(time.Duration(phase.At) * time.Millisecond)
+		end := at.Add(time.Duration(phase.Duration) * time.Millisecond)
+		message := phase.Error
+		if message == "" {
+			message = "simulated failure"
+		}
+		for t := at; t.Before(end); t = t.Add(interval) {
+			record := ReplayRecord{Time: t, Resource: phase.Resource, Duration: phase.Latency}
+			if phase.LatencyJitter > 0 {
+				record.Duration += rnd.Int63n(phase.LatencyJitter + 1)
+			}
+			if rnd.Float64() < phase.FailureRate {
+				record.Error = message
+			}
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// 按配置config和规则集rules对profile描述的合成负载做离线模拟，报告熔断打开和开始限流的时间
+// 模拟基于Replay，使用虚拟时间，结果中的时间从start开始
+func Simulate(config *GovernanceConfig, rules *RuleSet, profile *SimulationProfile, start time.Time) *ReplayReport {
+	return Replay(config, rules, profile.Records(start))
+}
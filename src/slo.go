@@ -0,0 +1,173 @@
+package governance
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rpc资源的SLO配置
+type SLOConfig struct {
+	Resource         string        `toml:"resource"`           // rpc资源名
+	Availability     float64       `toml:"availability"`       // 可用率目标，如0.999，为0时不检查可用率
+	P99              time.Duration `toml:"p99"`                // 99分位耗时目标，如500ms，为0时不检查耗时
+	MinFailThreshold int           `toml:"min_fail_threshold"` // 失败阈值的下限，小于1时按1处理
+	MaxFailThreshold int           `toml:"max_fail_threshold"` // 失败阈值的上限，为0时不调整失败阈值
+	MinQPS           float64       `toml:"min_qps"`            // 限流QPS的下限
+	MaxQPS           float64       `toml:"max_qps"`            // 限流QPS的上限，为0时不调整限流
+}
+
+// 自动调整的字段
+const (
+	TunedFailThreshold = "fail_threshold"
+	TunedQPS           = "qps"
+)
+
+// 每次调整限流QPS的比例
+const sloQPSStep = 0.1
+
+// 阈值调整记录
+type ThresholdChange struct {
+	Time         time.Time
+	Resource     string
+	Field        string        // 调整的字段，见TunedFailThreshold
+	Availability float64       // 本周期观测到的可用率
+	P99          time.Duration // 本周期观测到的99分位耗时
+	Old          float64       // 调整前的值
+	New          float64       // 调整后的值
+}
+
+// 根据SLO自动调整熔断失败阈值和限流QPS的控制器：
+// 可用率低于目标时降低失败阈值以更早熔断，错误数低于错误预算的一半时逐步放宽失败阈值；
+// 可用率或99分位耗时未达标时按比例降低限流QPS以减轻下游负载，两者都有一半以上余量时逐步提高
+// 失败阈值写入熔断器的自动调整层，只覆盖该字段，手工设置的其他配置仍然生效
+type SLOController struct {
+	Breaker  *Breaker
+	Limiter  *Limiter // 调整QPS的限流器，QPS对限流器的所有调用方生效，通常只为一个入口资源配置MaxQPS，为空时不调整限流
+	SLOs     []SLOConfig
+	AuditLog func(change ThresholdChange) // 阈值调整的审计记录，为空时不记录
+
+	sync.Mutex
+	last map[string]Stat // 上个周期的调用统计
+}
+
+// 创建SLO控制器
+func NewSLOController(breaker *Breaker, slos []SLOConfig) *SLOController {
+	return &SLOController{
+		Breaker: breaker,
+		SLOs:    slos,
+		last:    make(map[string]Stat),
+	}
+}
+
+// 按本周期的调用统计调整阈值，interval为调用周期，用于统计99分位耗时，最长为1分钟
+func (c *SLOController) Evaluate(interval time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	stats := c.Breaker.Stats()
+	for _, slo := range c.SLOs {
+		cur := stats[slo.Resource]
+		prev := c.last[slo.Resource]
+		c.last[slo.Resource] = cur
+
+		delta := Stat{Requests: cur.Requests - prev.Requests, Failures: cur.Failures - prev.Failures}
+		if delta.Requests == 0 {
+			continue
+		}
+		change := ThresholdChange{
+			Resource:     slo.Resource,
+			Availability: 1 - delta.ErrorRate(),
+			P99:          c.Breaker.P99(slo.Resource, interval),
+		}
+		budget := 1 - slo.Availability
+		// 未配置的目标视为达标且有余量
+		availabilityMissed := slo.Availability > 0 && change.Availability < slo.Availability
+		availabilityHealthy := slo.Availability == 0 || delta.ErrorRate() < budget/2
+		latencyMissed := slo.P99 > 0 && change.P99 > slo.P99
+		latencyHealthy := slo.P99 == 0 || change.P99 < slo.P99/2
+
+		if slo.MaxFailThreshold > 0 {
+			c.tuneFailThreshold(slo, change, availabilityMissed, availabilityHealthy)
+		}
+		if c.Limiter != nil && slo.MaxQPS > 0 {
+			c.tuneQPS(slo, change, availabilityMissed || latencyMissed, availabilityHealthy && latencyHealthy)
+		}
+	}
+}
+
+// 调整失败阈值，只写入自动调整层的失败阈值字段
+func (c *SLOController) tuneFailThreshold(slo SLOConfig, change ThresholdChange, missed, healthy bool) {
+	c.Breaker.RLock()
+	old := c.Breaker.configOf(slo.Resource).FailThreshold
+	c.Breaker.RUnlock()
+
+	// 自动调整层中失败阈值为0表示继承，不能调整到0
+	min := slo.MinFailThreshold
+	if min < 1 {
+		min = 1
+	}
+	threshold := old
+	if missed && threshold > min {
+		threshold--
+	} else if !missed && healthy && threshold < slo.MaxFailThreshold {
+		threshold++
+	}
+	if threshold == old {
+		return
+	}
+
+	tuned := Config{}
+	if t := c.Breaker.TunedConfig(slo.Resource); t != nil {
+		tuned = *t
+	}
+	tuned.FailThreshold = threshold
+	c.Breaker.SetTunedConfig(slo.Resource, &tuned)
+
+	change.Field, change.Old, change.New = TunedFailThreshold, float64(old), float64(threshold)
+	c.audit(change)
+}
+
+// 调整限流QPS，未达标时按sloQPSStep降低，有余量时按sloQPSStep提高，不超过上下限
+func (c *SLOController) tuneQPS(slo SLOConfig, change ThresholdChange, missed, healthy bool) {
+	config := *c.Limiter.GetConfig()
+	old := config.QPS
+
+	switch {
+	case missed:
+		config.QPS = math.Max(old*(1-sloQPSStep), slo.MinQPS)
+	case healthy:
+		config.QPS = math.Min(old*(1+sloQPSStep), slo.MaxQPS)
+	}
+	if config.QPS == old {
+		return
+	}
+	c.Limiter.SetConfig(&config)
+
+	change.Field, change.Old, change.New = TunedQPS, old, config.QPS
+	c.audit(change)
+}
+
+// 记录阈值调整
+func (c *SLOController) audit(change ThresholdChange) {
+	change.Time = time.Now()
+	if c.AuditLog != nil {
+		c.AuditLog(change)
+	}
+}
+
+// 定时调整阈值，ctx结束时返回
+func (c *SLOController) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Evaluate(interval)
+		}
+	}
+}
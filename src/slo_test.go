@@ -0,0 +1,66 @@
+package governance
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSLOControllerTunesOverrideLayer(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 5
+	breaker := newBreaker(&config)
+	breaker.SetConfig("r", &Config{FailThreshold: 5, SuccThreshold: 3})
+	limiter := NewLimiter(&LimiterConfig{QPS: 100, Burst: 10})
+	c := NewSLOController(breaker, []SLOConfig{{
+		Resource:         "r",
+		Availability:     0.99,
+		MinFailThreshold: 2,
+		MaxFailThreshold: 10,
+		MinQPS:           50,
+		MaxQPS:           200,
+	}})
+	c.Limiter = limiter
+	var changes []ThresholdChange
+	c.AuditLog = func(change ThresholdChange) { changes = append(changes, change) }
+
+	errFail := errors.New("fail")
+	breaker.setSucc("r")
+	breaker.setFail("r", errFail)
+	c.Evaluate(0)
+
+	if got := breaker.TunedConfig("r"); got == nil || got.FailThreshold != 4 {
+		t.Fatalf("tuned config = %+v, want fail_threshold 4", got)
+	}
+	if got := breaker.GetConfig("r"); got.FailThreshold != 5 {
+		t.Errorf("resource config changed to fail_threshold %d", got.FailThreshold)
+	}
+	effective := breaker.ResolveEffectiveConfig("r")
+	if effective.Config.FailThreshold != 4 || effective.Sources["fail_threshold"] != LayerTuned || effective.Config.SuccThreshold != 3 {
+		t.Errorf("effective config = %+v, sources %v", effective.Config, effective.Sources)
+	}
+	if got := limiter.GetConfig().QPS; got != 90 {
+		t.Errorf("qps = %v, want 90", got)
+	}
+	if len(changes) != 2 || changes[0].Field != TunedFailThreshold || changes[1].Field != TunedQPS {
+		t.Errorf("audit log = %+v", changes)
+	}
+}
+
+// 未设置下限时失败阈值不低于1，自动调整层的0表示继承
+func TestSLOControllerFailThresholdFloor(t *testing.T) {
+	config := DefaultConfig()
+	config.FailThreshold = 2
+	breaker := newBreaker(&config)
+	c := NewSLOController(breaker, []SLOConfig{{Resource: "r", Availability: 0.99, MaxFailThreshold: 10}})
+
+	errFail := errors.New("fail")
+	for i := 0; i < 4; i++ {
+		breaker.ForceState("r", CloseStatus)
+		breaker.setSucc("r")
+		breaker.setFail("r", errFail)
+		c.Evaluate(0)
+	}
+	if got := breaker.ResolveEffectiveConfig("r").Config.FailThreshold; got != 1 {
+		t.Fatalf("fail_threshold = %d, want 1", got)
+	}
+}
@@ -0,0 +1,69 @@
+package governance
+
+import (
+	"sync"
+	"time"
+)
+
+// 削峰配置
+type SpikeArrestConfig struct {
+	Slice       int64 `toml:"slice"`         // 时间片长度，单位毫秒，为0时使用默认值100毫秒
+	MaxPerSlice int   `toml:"max_per_slice"` // 每个时间片内允许的最大请求数
+}
+
+// 时间片长度的默认值
+const defaultSpikeSlice = 100 * time.Millisecond
+
+// 时间片长度
+func (config *SpikeArrestConfig) slice() time.Duration {
+	if config.Slice <= 0 {
+		return defaultSpikeSlice
+	}
+
+	return time.Duration(config.Slice) * time.Millisecond
+}
+
+// 调用方当前时间片的计数
+type sliceCounter struct {
+	slice int64 // 时间片序号
+	count int   // 时间片内的请求数
+}
+
+// 削峰限流器，将请求均匀分布到亚秒级的时间片内，防止平均QPS未超限时的瞬时突发
+type SpikeArrester struct {
+	Config *SpikeArrestConfig
+	sync.Mutex
+	counters map[string]*sliceCounter
+}
+
+// 创建削峰限流器
+func NewSpikeArrester(config *SpikeArrestConfig) *SpikeArrester {
+	return &SpikeArrester{
+		Config:   config,
+		counters: make(map[string]*sliceCounter),
+	}
+}
+
+// 判断调用方key的请求是否允许执行
+func (s *SpikeArrester) Allow(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	slice := time.Now().UnixNano() / int64(s.Config.slice())
+	c, ok := s.counters[key]
+	if !ok {
+		c = &sliceCounter{}
+		s.counters[key] = c
+	}
+	if c.slice != slice {
+		c.slice = slice
+		c.count = 0
+	}
+
+	if c.count >= s.Config.MaxPerSlice {
+		return false
+	}
+	c.count++
+
+	return true
+}
@@ -0,0 +1,130 @@
+package governance
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	sqlCommaPattern  = regexp.MustCompile(`\s*,\s*`)
+	sqlOpenPattern   = regexp.MustCompile(`\(\s+`)
+	sqlClosePattern  = regexp.MustCompile(`\s+\)`)
+	sqlInListPattern = regexp.MustCompile(`\bin ?\(\?(?:, \?)*\)`)
+	sqlValuesPattern = regexp.MustCompile(`\bvalues ?\(\?(?:, \?)*\)(?:, \(\?(?:, \?)*\))*`)
+)
+
+// 计算SQL语句的指纹：去掉注释，字面量和占位符替换为?，IN列表和批量VALUES折叠为(?+)，
+// 关键字和标识符转为小写，空白合并为一个空格，参数不同的同一类语句得到相同的指纹
+func FingerprintSQL(stmt string) string {
+	var b strings.Builder
+	space := false
+	emit := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case c == '-' && i+1 < len(stmt) && stmt[i+1] == '-', c == '#':
+			// 单行注释
+			for i < len(stmt) && stmt[i] != '\n' {
+				i++
+			}
+			space = true
+		case c == '/' && i+1 < len(stmt) && stmt[i+1] == '*':
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				i = len(stmt)
+			} else {
+				i += end + 4
+			}
+			space = true
+		case c == '\'':
+			// 字符串字面量，支持''和\'转义
+			i++
+			for i < len(stmt) {
+				if stmt[i] == '\\' {
+					i += 2
+					continue
+				}
+				if stmt[i] == '\'' {
+					if i+1 < len(stmt) && stmt[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			i++
+			emit("?")
+		case c == '"' || c == '`':
+			// 带引号的标识符
+			end := strings.IndexByte(stmt[i+1:], c)
+			if end < 0 {
+				end = len(stmt) - i - 1
+			}
+			emit(strings.ToLower(stmt[i+1 : i+1+end]))
+			i += end + 2
+		case c >= '0' && c <= '9', c == '.' && i+1 < len(stmt) && stmt[i+1] >= '0' && stmt[i+1] <= '9':
+			// 数字字面量，包括小数、科学计数法和0x十六进制
+			i++
+			for i < len(stmt) && (isSQLIdentByte(stmt[i]) || stmt[i] == '.') {
+				i++
+			}
+			emit("?")
+		case c == '?' || ((c == '$' || c == ':') && i+1 < len(stmt) && isSQLIdentByte(stmt[i+1])):
+			// 占位符?、$1和:name
+			i++
+			for c != '?' && i < len(stmt) && isSQLIdentByte(stmt[i]) {
+				i++
+			}
+			emit("?")
+		case isSQLIdentByte(c):
+			start := i
+			for i < len(stmt) && (isSQLIdentByte(stmt[i]) || stmt[i] == '.') {
+				i++
+			}
+			emit(strings.ToLower(stmt[start:i]))
+		default:
+			emit(string(c))
+			i++
+		}
+	}
+
+	s := b.String()
+	s = sqlCommaPattern.ReplaceAllString(s, ", ")
+	s = sqlOpenPattern.ReplaceAllString(s, "(")
+	s = sqlClosePattern.ReplaceAllString(s, ")")
+	s = sqlInListPattern.ReplaceAllString(s, "in (?+)")
+	s = sqlValuesPattern.ReplaceAllString(s, "values (?+)")
+
+	return strings.TrimSuffix(strings.TrimSpace(s), ";")
+}
+
+func isSQLIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// 数据库db上SQL语句的资源标识，方法为操作和主表，扩展维度为语句指纹的短哈希，
+// 可按方法配置熔断规则，同时按语句区分统计，无需为每条语句手工标注
+func SQLResourceKey(db, stmt string) ResourceKey {
+	fp := FingerprintSQL(stmt)
+	h := fnv.New64a()
+	h.Write([]byte(fp))
+
+	return ResourceKey{
+		Service: db,
+		Method:  NormalizeSQL(fp),
+		Extra:   strconv.FormatUint(h.Sum64(), 16),
+	}
+}
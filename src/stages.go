@@ -0,0 +1,53 @@
+package governance
+
+import (
+	"context"
+	"errors"
+)
+
+// Governance.Execute的处理阶段，可在GovernanceConfig.Stages中按rpc资源关闭
+const (
+	StageLimiter = "limiter"
+	StageBreaker = "breaker"
+	StageRetry   = "retry"
+)
+
+// 判断rpc资源r是否启用处理阶段stage，先按rpc资源名查找配置，再按服务名查找，classDisabled为请求类别关闭的处理阶段
+func (g *Governance) stageEnabled(r, stage string, classDisabled []string) bool {
+	disabled, ok := g.Config.Stages[r]
+	if !ok {
+		disabled = g.Config.Stages[DecodeResourceKey(r).Service]
+	}
+	for _, list := range [][]string{disabled, classDisabled} {
+		for _, s := range list {
+			if s == stage {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// 治理流程默认的重试分类器，被熔断或限流拒绝的调用不重试
+func retryClassifier(err error) Outcome {
+	if errors.Is(err, ErrBreakerOpen) || errors.Is(err, ErrRateLimited) {
+		return OutcomeIgnore
+	}
+
+	return DefaultClassifier(err)
+}
+
+// 按rpc资源r启用的处理阶段执行一次调用，不包含重试
+func (g *Governance) executeStages(ctx context.Context, r string, disabled []string, fn func(ctx context.Context) error) error {
+	if g.Limiter != nil && g.stageEnabled(r, StageLimiter, disabled) {
+		if err := g.Limiter.WaitN(ctx, r, CostFrom(ctx)); err != nil {
+			return err
+		}
+	}
+	if !g.stageEnabled(r, StageBreaker, disabled) {
+		return fn(ctx)
+	}
+
+	return g.Breaker.Execute(ctx, r, fn)
+}
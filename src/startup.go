@@ -0,0 +1,121 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// 启动预热期间请求被拒绝时返回的错误
+var ErrStartupRamp = errors.New("governance: rejected during startup ramp")
+
+// 进程启动预热配置
+type StartupRampConfig struct {
+	Window       int64   `toml:"window"`        // 预热时长，单位秒
+	InitialRatio float64 `toml:"initial_ratio"` // 启动时接收流量的比例，取值0~1，之后线性增长到1
+	QPS          float64 `toml:"qps"`           // 满载时的入口QPS，为0时按比例随机接收请求
+	Burst        int     `toml:"burst"`         // 按QPS预热时允许的突发请求数
+}
+
+// 进程级启动预热，启动后在预热时长内将入口接收的流量从InitialRatio逐步提高到100%，
+// 使缓存预热和代码路径完成JIT之前不承受满载；可通过OnWeight同步调整注册中心中的实例权重
+type StartupRamp struct {
+	Config   *StartupRampConfig
+	OnWeight func(weight float64) // 预热进度变化时调用，weight为当前接收比例，预热结束时以1调用
+
+	start    time.Time
+	limiter  *Limiter
+	rejected int64
+}
+
+// 创建启动预热，以当前时间作为启动时间
+func NewStartupRamp(config *StartupRampConfig) *StartupRamp {
+	s := &StartupRamp{
+		Config: config,
+		start:  time.Now(),
+	}
+	if config.QPS > 0 {
+		s.limiter = NewLimiter(&LimiterConfig{QPS: config.QPS, Burst: config.Burst})
+	}
+
+	return s
+}
+
+// now时刻接收流量的比例
+func (s *StartupRamp) Ratio(now time.Time) float64 {
+	window := time.Duration(s.Config.Window) * time.Second
+	elapsed := now.Sub(s.start)
+	if window <= 0 || elapsed >= window {
+		return 1
+	}
+
+	initial := s.Config.InitialRatio
+	if initial < 0 {
+		initial = 0
+	}
+
+	return initial + (1-initial)*float64(elapsed)/float64(window)
+}
+
+// 判断是否接收一个入口请求，预热结束后总是接收
+func (s *StartupRamp) Allow() bool {
+	ratio := s.Ratio(time.Now())
+	if ratio >= 1 {
+		return true
+	}
+
+	var ok bool
+	if s.limiter != nil {
+		s.limiter.Throttle("", ratio, time.Time{})
+		ok = s.limiter.Allow("")
+	} else {
+		ok = rand.Float64() < ratio
+	}
+	if !ok {
+		atomic.AddInt64(&s.rejected, 1)
+	}
+
+	return ok
+}
+
+// 预热期间被拒绝的请求数
+func (s *StartupRamp) Rejected() int64 {
+	return atomic.LoadInt64(&s.rejected)
+}
+
+// 预热期间每隔interval以当前比例调用OnWeight，预热结束时以1调用后返回
+func (s *StartupRamp) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ratio := s.Ratio(time.Now())
+		if s.OnWeight != nil {
+			s.OnWeight(ratio)
+		}
+		if ratio >= 1 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// http中间件，预热期间超出当前比例的请求返回503并建议1秒后重试
+func StartupRampHandler(next http.Handler, s *StartupRamp) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Allow() {
+			AdvertiseBackpressure(w.Header().Set, 1, time.Second)
+			http.Error(w, ErrStartupRamp.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
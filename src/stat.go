@@ -0,0 +1,135 @@
+package governance
+
+import "sort"
+
+// 熔断打开的原因
+const (
+	TripFailThreshold   = "fail threshold reached"  // 关闭状态下失败次数达到阈值
+	TripHalfOpenFailure = "half-open probe failed"  // 半打开状态下探测请求失败
+	TripForced          = "forced open"             // 被强制打开
+	TripGauge           = "gauge threshold reached" // 外部指标如消费积压达到阈值
+)
+
+// rpc资源调用统计
+type Stat struct {
+	Requests int64 `json:"requests"` // 请求次数
+	Failures int64 `json:"failures"` // 失败次数
+
+	DryRunRejects int64 `json:"dry_run_rejects"` // 试运行模式下会被拒绝的次数
+
+	LastError     string `json:"last_error,omitempty"`  // 最近一次调用失败的错误
+	LastErrorTime int64  `json:"last_error_time"`       // 最近一次调用失败的时间
+	TripReason    string `json:"trip_reason,omitempty"` // 最近一次熔断打开的原因
+	TripTime      int64  `json:"trip_time"`             // 最近一次熔断打开的时间
+}
+
+// 错误率
+func (s Stat) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+
+	return float64(s.Failures) / float64(s.Requests)
+}
+
+// 记录rpc资源r的一次调用结果，调用方需持有锁
+func (breaker *Breaker) record(r string, err error) {
+	s := breaker.stat(r)
+	s.Requests++
+	if err != nil {
+		s.Failures++
+		s.LastError = err.Error()
+		s.LastErrorTime = breaker.now().Unix()
+	}
+}
+
+// 记录rpc资源r熔断由from状态打开的原因，调用方需持有锁
+func (breaker *Breaker) trip(r string, from BreakerStatus, reason string) {
+	s := breaker.stat(r)
+	s.TripReason = reason
+	s.TripTime = breaker.now().Unix()
+	breaker.emit(r, from, OpenStatus, reason)
+}
+
+// 获取rpc资源r的调用统计，不存在时创建，调用方需持有锁
+func (breaker *Breaker) stat(r string) *Stat {
+	s, ok := breaker.stats[r]
+	if !ok {
+		s = &Stat{}
+		breaker.stats[r] = s
+	}
+
+	return s
+}
+
+// 记录rpc资源r在试运行模式下会被拒绝的一次调用
+func (breaker *Breaker) recordDryRun(r string) {
+	breaker.Lock()
+	defer breaker.unlockCounters()
+
+	breaker.stat(r).DryRunRejects++
+}
+
+// 获取所有rpc资源的调用统计
+func (breaker *Breaker) Stats() map[string]Stat {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	stats := make(map[string]Stat, len(breaker.stats))
+	for r, s := range breaker.stats {
+		stats[r] = *s
+	}
+
+	return stats
+}
+
+// 获取所有rpc资源的熔断状态和调用统计，按资源名排序
+func (breaker *Breaker) Snapshot() []ResourceState {
+	return breaker.SnapshotByTags(nil)
+}
+
+// 获取标签匹配filter的rpc资源的熔断状态和调用统计，按资源名排序
+func (breaker *Breaker) SnapshotByTags(filter TagFilter) []ResourceState {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	states := make([]ResourceState, 0, len(breaker.stats))
+	for r, s := range breaker.stats {
+		tags := breaker.configOf(r).Tags
+		if !filter.Match(tags) {
+			continue
+		}
+		states = append(states, ResourceState{
+			Resource: r,
+			Status:   breaker.status(r),
+			Tags:     tags,
+			Stat:     *s,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Resource < states[j].Resource })
+
+	return states
+}
+
+// 按服务汇总各方法的调用统计，rpc资源名按ResourceKey解码
+func (breaker *Breaker) ServiceStats() map[string]Stat {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	stats := make(map[string]Stat)
+	for r, s := range breaker.stats {
+		service := DecodeResourceKey(r).Service
+		rollup := stats[service]
+		rollup.Requests += s.Requests
+		rollup.Failures += s.Failures
+		rollup.DryRunRejects += s.DryRunRejects
+		stats[service] = rollup
+	}
+
+	return stats
+}
+
+// 获取服务service汇总后的调用统计
+func (breaker *Breaker) ServiceStat(service string) Stat {
+	return breaker.ServiceStats()[service]
+}
@@ -0,0 +1,368 @@
+package governance
+
+import (
+	"sort"
+	"time"
+)
+
+// 单个rpc资源的熔断状态，对应proto/state.proto中的BreakerState
+type BreakerState struct {
+	Resource    string
+	Status      BreakerStatus
+	FailCount   int
+	SuccCount   int
+	ReqCount    int
+	OpenTime    int64
+	OpenTimeout int64
+	ReopenCount int
+	Instance    string
+	UpdatedAt   time.Time
+}
+
+// 单个调用方的令牌桶状态，对应proto/state.proto中的LimiterState
+type LimiterState struct {
+	Key         string
+	Tokens      float64
+	LastRefill  time.Time
+	Scale       float64
+	PausedUntil time.Time
+}
+
+// 实例的完整状态快照，对应proto/state.proto中的StateSnapshot
+type StateSnapshot struct {
+	Instance string
+	Time     time.Time
+	Breakers []BreakerState
+	Limiters []LimiterState
+	Stats    []ResourceState
+}
+
+// 时间转换为unix毫秒，零值为0
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixMilli()
+}
+
+// unix毫秒转换为时间，0为零值
+func fromUnixMilli(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(ms)
+}
+
+// 所有rpc资源的熔断状态，按资源名排序
+func (breaker *Breaker) States(instance string) []BreakerState {
+	breaker.RLock()
+	defer breaker.RUnlock()
+
+	now := breaker.now()
+	states := make([]BreakerState, 0, len(breaker.R))
+	for r, v := range breaker.R {
+		states = append(states, BreakerState{
+			Resource:    r,
+			Status:      breaker.status(r),
+			FailCount:   v.FailCount,
+			SuccCount:   v.SuccCount,
+			ReqCount:    v.ReqCount,
+			OpenTime:    v.OpenTime,
+			OpenTimeout: int64(v.OpenTimeout / time.Second),
+			ReopenCount: v.ReopenCount,
+			Instance:    instance,
+			UpdatedAt:   now,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Resource < states[j].Resource })
+
+	return states
+}
+
+// 所有调用方的令牌桶状态，按调用方排序
+func (l *Limiter) States() []LimiterState {
+	l.Lock()
+	defer l.Unlock()
+
+	states := make([]LimiterState, 0, len(l.buckets))
+	for key, b := range l.buckets {
+		states = append(states, LimiterState{
+			Key:         key,
+			Tokens:      b.tokens,
+			LastRefill:  b.last,
+			Scale:       b.scale,
+			PausedUntil: b.paused,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Key < states[j].Key })
+
+	return states
+}
+
+// 编码为protobuf格式
+func (s BreakerState) Marshal() []byte {
+	var w protoWriter
+	s.write(&w)
+	return w.buf
+}
+
+func (s BreakerState) write(w *protoWriter) {
+	w.string(1, s.Resource)
+	w.int64(2, int64(s.Status))
+	w.int64(3, int64(s.FailCount))
+	w.int64(4, int64(s.SuccCount))
+	w.int64(5, int64(s.ReqCount))
+	w.int64(6, s.OpenTime)
+	w.int64(7, s.OpenTimeout)
+	w.int64(8, int64(s.ReopenCount))
+	w.string(9, s.Instance)
+	w.int64(10, unixMilli(s.UpdatedAt))
+}
+
+// 从protobuf格式解码
+func (s *BreakerState) Unmarshal(data []byte) error {
+	return readProto(data, func(f protoField) {
+		switch f.num {
+		case 1:
+			s.Resource = f.string()
+		case 2:
+			s.Status = BreakerStatus(f.int64())
+		case 3:
+			s.FailCount = int(int32(f.int64()))
+		case 4:
+			s.SuccCount = int(int32(f.int64()))
+		case 5:
+			s.ReqCount = int(int32(f.int64()))
+		case 6:
+			s.OpenTime = f.int64()
+		case 7:
+			s.OpenTimeout = f.int64()
+		case 8:
+			s.ReopenCount = int(int32(f.int64()))
+		case 9:
+			s.Instance = f.string()
+		case 10:
+			s.UpdatedAt = fromUnixMilli(f.int64())
+		}
+	})
+}
+
+// 编码为protobuf格式
+func (s LimiterState) Marshal() []byte {
+	var w protoWriter
+	s.write(&w)
+	return w.buf
+}
+
+func (s LimiterState) write(w *protoWriter) {
+	w.string(1, s.Key)
+	w.double(2, s.Tokens)
+	w.int64(3, unixMilli(s.LastRefill))
+	w.double(4, s.Scale)
+	w.int64(5, unixMilli(s.PausedUntil))
+}
+
+// 从protobuf格式解码
+func (s *LimiterState) Unmarshal(data []byte) error {
+	return readProto(data, func(f protoField) {
+		switch f.num {
+		case 1:
+			s.Key = f.string()
+		case 2:
+			s.Tokens = f.double()
+		case 3:
+			s.LastRefill = fromUnixMilli(f.int64())
+		case 4:
+			s.Scale = f.double()
+		case 5:
+			s.PausedUntil = fromUnixMilli(f.int64())
+		}
+	})
+}
+
+// 编码为protobuf格式，状态名无法识别时按close编码
+func (op ForcedOperation) Marshal() []byte {
+	var w protoWriter
+	w.string(1, op.Resource)
+	w.int64(2, int64(statusByName[op.Status]))
+	w.int64(3, unixMilli(op.Expire))
+	w.string(4, op.Operator)
+	return w.buf
+}
+
+// 从protobuf格式解码
+func (op *ForcedOperation) Unmarshal(data []byte) error {
+	op.Status = statusName(CloseStatus)
+	return readProto(data, func(f protoField) {
+		switch f.num {
+		case 1:
+			op.Resource = f.string()
+		case 2:
+			op.Status = statusName(BreakerStatus(f.int64()))
+		case 3:
+			op.Expire = fromUnixMilli(f.int64())
+		case 4:
+			op.Operator = f.string()
+		}
+	})
+}
+
+// 将熔断配置编码为proto/control.proto中的BreakerConfig，时长按整秒编码，未设置的disabled省略以继承低层级的配置
+func MarshalBreakerConfig(config *Config) []byte {
+	var w protoWriter
+	w.int64(1, int64(config.FailThreshold))
+	w.int64(2, int64(config.SuccThreshold))
+	w.int64(3, int64(config.OpenTimeout/time.Second))
+	w.int64(4, int64(config.MaxOpenTimeout/time.Second))
+	w.int64(5, int64(config.MinRequestVolume))
+	w.optionalBool(6, config.Disabled)
+	w.bool(7, config.DryRun)
+	return w.buf
+}
+
+// 从proto/control.proto中的BreakerConfig解码熔断配置，消息中没有disabled字段时Disabled为空
+func UnmarshalBreakerConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	err := readProto(data, func(f protoField) {
+		switch f.num {
+		case 1:
+			config.FailThreshold = int(int32(f.int64()))
+		case 2:
+			config.SuccThreshold = int(int32(f.int64()))
+		case 3:
+			config.OpenTimeout = time.Duration(f.int64()) * time.Second
+		case 4:
+			config.MaxOpenTimeout = time.Duration(f.int64()) * time.Second
+		case 5:
+			config.MinRequestVolume = int(int32(f.int64()))
+		case 6:
+			config.Disabled = Bool(f.bool())
+		case 7:
+			config.DryRun = f.bool()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// 编码为proto/control.proto中的ResourceStat
+func writeResourceStat(w *protoWriter, s ResourceState) {
+	w.string(1, s.Resource)
+	w.int64(2, int64(s.Status))
+	w.int64(3, s.Requests)
+	w.int64(4, s.Failures)
+	w.int64(5, s.DryRunRejects)
+	w.string(6, s.LastError)
+	w.int64(7, s.LastErrorTime)
+	w.string(8, s.TripReason)
+	w.int64(9, s.TripTime)
+}
+
+// 从proto/control.proto中的ResourceStat解码
+func readResourceStat(data []byte) (ResourceState, error) {
+	var s ResourceState
+	err := readProto(data, func(f protoField) {
+		switch f.num {
+		case 1:
+			s.Resource = f.string()
+		case 2:
+			s.Status = BreakerStatus(f.int64())
+		case 3:
+			s.Requests = f.int64()
+		case 4:
+			s.Failures = f.int64()
+		case 5:
+			s.DryRunRejects = f.int64()
+		case 6:
+			s.LastError = f.string()
+		case 7:
+			s.LastErrorTime = f.int64()
+		case 8:
+			s.TripReason = f.string()
+		case 9:
+			s.TripTime = f.int64()
+		}
+	})
+
+	return s, err
+}
+
+// 采集实例instance的完整状态快照，limiter为空时不包含限流状态
+func NewStateSnapshot(instance string, breaker *Breaker, limiter *Limiter) *StateSnapshot {
+	s := &StateSnapshot{
+		Instance: instance,
+		Time:     time.Now(),
+		Breakers: breaker.States(instance),
+		Stats:    breaker.Snapshot(),
+	}
+	if limiter != nil {
+		s.Limiters = limiter.States()
+	}
+
+	return s
+}
+
+// 编码为protobuf格式
+func (s *StateSnapshot) Marshal() []byte {
+	var w protoWriter
+	w.string(1, s.Instance)
+	w.int64(2, unixMilli(s.Time))
+	for _, b := range s.Breakers {
+		var m protoWriter
+		b.write(&m)
+		w.bytes(3, m.buf)
+	}
+	for _, l := range s.Limiters {
+		var m protoWriter
+		l.write(&m)
+		w.bytes(4, m.buf)
+	}
+	for _, st := range s.Stats {
+		var m protoWriter
+		writeResourceStat(&m, st)
+		w.bytes(5, m.buf)
+	}
+
+	return w.buf
+}
+
+// 从protobuf格式解码
+func (s *StateSnapshot) Unmarshal(data []byte) error {
+	var err error
+	perr := readProto(data, func(f protoField) {
+		if err != nil {
+			return
+		}
+		switch f.num {
+		case 1:
+			s.Instance = f.string()
+		case 2:
+			s.Time = fromUnixMilli(f.int64())
+		case 3:
+			var b BreakerState
+			if err = b.Unmarshal(f.message()); err == nil {
+				s.Breakers = append(s.Breakers, b)
+			}
+		case 4:
+			var l LimiterState
+			if err = l.Unmarshal(f.message()); err == nil {
+				s.Limiters = append(s.Limiters, l)
+			}
+		case 5:
+			var st ResourceState
+			if st, err = readResourceStat(f.message()); err == nil {
+				s.Stats = append(s.Stats, st)
+			}
+		}
+	})
+	if perr != nil {
+		return perr
+	}
+
+	return err
+}
@@ -0,0 +1,22 @@
+package governance
+
+import (
+	"testing"
+	"time"
+)
+
+// disabled区分未设置、false和true
+func TestBreakerConfigDisabledPresence(t *testing.T) {
+	for _, disabled := range []*bool{nil, Bool(false), Bool(true)} {
+		config, err := UnmarshalBreakerConfig(MarshalBreakerConfig(&Config{FailThreshold: 3, OpenTimeout: 30 * time.Second, Disabled: disabled}))
+		if err != nil {
+			t.Fatalf("UnmarshalBreakerConfig: %v", err)
+		}
+		if config.FailThreshold != 3 || config.OpenTimeout != 30*time.Second {
+			t.Fatalf("config = %+v", config)
+		}
+		if (config.Disabled == nil) != (disabled == nil) || (disabled != nil && *config.Disabled != *disabled) {
+			t.Fatalf("disabled = %v, want %v", config.Disabled, disabled)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package governance
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 状态机的状态转换
+type Transition struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Event  string `json:"event"` // 触发转换的事件
+	Guard  string `json:"guard"` // 转换需满足的条件，按当前配置展开，为空表示无条件
+	Action string `json:"action"`
+}
+
+// 以数据形式描述的状态机，可在运行时查询并渲染为DOT或Mermaid图
+type StateMachine struct {
+	Name        string       `json:"name"`
+	Initial     string       `json:"initial"`
+	States      []string     `json:"states"`
+	Transitions []Transition `json:"transitions"`
+}
+
+// 熔断状态的名称，与管理接口强制设置状态时使用的名称一致
+func statusName(status BreakerStatus) string {
+	for name, s := range statusByName {
+		if s == status {
+			return name
+		}
+	}
+
+	return fmt.Sprint(int(status))
+}
+
+// 按rpc资源r当前生效的配置导出熔断状态机
+func (breaker *Breaker) StateMachine(r string) StateMachine {
+	breaker.RLock()
+	config := *breaker.configOf(r)
+	breaker.RUnlock()
+
+	closeName, halfOpenName, openName := statusName(CloseStatus), statusName(HalfOpenStatus), statusName(OpenStatus)
+
+	tripGuard := fmt.Sprintf("failures >= %d within %v", config.FailThreshold, config.statWindow())
+	if len(config.ErrorWeights) > 0 {
+		tripGuard = fmt.Sprintf("weighted failures >= %d within %v", config.FailThreshold, config.statWindow())
+	}
+	if config.MinRequestVolume > 0 {
+		tripGuard += fmt.Sprintf(" && requests >= %d", config.MinRequestVolume)
+	}
+	if grace := config.RecoveryGrace; grace > 0 {
+		tripGuard += fmt.Sprintf(" && closed for >= %v", grace)
+	}
+	timeout, max := config.openTimeouts()
+	openGuard := fmt.Sprintf("open for %v", timeout)
+	if max > timeout {
+		openGuard = fmt.Sprintf("open for %v, doubling on each reopen up to %v", timeout, max)
+	}
+	failureEvent := "failure"
+	if config.LatencyBudget > 0 {
+		failureEvent = fmt.Sprintf("failure or latency > %dms", config.LatencyBudget)
+	}
+
+	m := StateMachine{
+		Name:    "breaker:" + r,
+		Initial: closeName,
+		States:  []string{closeName, halfOpenName, openName},
+		Transitions: []Transition{
+			{From: closeName, To: openName, Event: failureEvent, Guard: tripGuard, Action: TripFailThreshold},
+			{From: openName, To: halfOpenName, Event: "tick", Guard: openGuard, Action: ReasonOpenTimeout},
+			{From: halfOpenName, To: openName, Event: failureEvent, Action: TripHalfOpenFailure},
+			{From: halfOpenName, To: closeName, Event: "success", Guard: fmt.Sprintf("successes >= %d", config.SuccThreshold), Action: ReasonSuccThreshold},
+			{From: "*", To: openName, Event: "force", Action: TripForced},
+			{From: "*", To: closeName, Event: "force", Action: ReasonForced},
+			{From: "*", To: openName, Event: "gauge", Guard: "gauge >= threshold", Action: TripGauge},
+			{From: openName, To: halfOpenName, Event: "gauge", Guard: "gauge < resume", Action: ReasonGaugeRecovered},
+		},
+	}
+	if config.disabled() || config.DryRun {
+		// 关闭或试运行时状态照常流转，但不拒绝调用
+		for i := range m.Transitions {
+			if m.Transitions[i].To == openName {
+				m.Transitions[i].Action += " (calls not rejected)"
+			}
+		}
+	}
+
+	return m
+}
+
+// 按当前配置导出单个调用方令牌桶的限流状态机
+func (l *Limiter) StateMachine() StateMachine {
+	config := *l.GetConfig()
+
+	refill := fmt.Sprintf("refill at %g/s up to %d", config.QPS, config.Burst)
+	m := StateMachine{
+		Name:    "limiter",
+		Initial: "available",
+		States:  []string{"available", "rejecting"},
+		Transitions: []Transition{
+			{From: "rejecting", To: "available", Event: "tick", Guard: "tokens >= cost", Action: refill},
+		},
+	}
+
+	rejectAction := "reject with ErrRateLimited"
+	if config.DryRun {
+		rejectAction = "count dry-run reject and allow"
+	}
+	if config.Delay && !config.DryRun {
+		m.States = []string{"available", "delaying", "rejecting"}
+		m.Transitions = append(m.Transitions,
+			Transition{From: "available", To: "delaying", Event: "request", Guard: "tokens < cost", Action: "wait for refill"},
+			Transition{From: "delaying", To: "rejecting", Event: "request", Guard: fmt.Sprintf("delay > %v", config.maxDelay()), Action: rejectAction},
+			Transition{From: "delaying", To: "available", Event: "tick", Guard: "tokens >= cost", Action: refill},
+		)
+	} else {
+		m.Transitions = append(m.Transitions,
+			Transition{From: "available", To: "rejecting", Event: "request", Guard: "tokens < cost", Action: rejectAction},
+		)
+	}
+
+	return m
+}
+
+// 转换的标签，格式为 事件 [条件] / 动作
+func (t Transition) label() string {
+	label := t.Event
+	if t.Guard != "" {
+		label += " [" + t.Guard + "]"
+	}
+	if t.Action != "" {
+		label += " / " + t.Action
+	}
+
+	return label
+}
+
+// 展开From为*的转换，*表示任意其他状态
+func (m StateMachine) expand() []Transition {
+	var transitions []Transition
+	for _, t := range m.Transitions {
+		if t.From != "*" {
+			transitions = append(transitions, t)
+			continue
+		}
+		for _, s := range m.States {
+			if s != t.To {
+				e := t
+				e.From = s
+				transitions = append(transitions, e)
+			}
+		}
+	}
+
+	return transitions
+}
+
+// 渲染为Graphviz DOT格式
+func (m StateMachine) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", m.Name)
+	b.WriteString("\trankdir=LR;\n")
+	fmt.Fprintf(&b, "\t%q [shape=doublecircle];\n", m.Initial)
+	for _, s := range m.States {
+		if s != m.Initial {
+			fmt.Fprintf(&b, "\t%q [shape=circle];\n", s)
+		}
+	}
+	for _, t := range m.expand() {
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", t.From, t.To, t.label())
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// 渲染为Mermaid stateDiagram格式
+func (m StateMachine) Mermaid() string {
+	id := func(s string) string {
+		return strings.ReplaceAll(s, "-", "_")
+	}
+
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	for _, s := range m.States {
+		if id(s) != s {
+			fmt.Fprintf(&b, "\tstate \"%s\" as %s\n", s, id(s))
+		}
+	}
+	fmt.Fprintf(&b, "\t[*] --> %s\n", id(m.Initial))
+	for _, t := range m.expand() {
+		// Mermaid标签中的冒号会被解析为分隔符
+		fmt.Fprintf(&b, "\t%s --> %s : %s\n", id(t.From), id(t.To), strings.ReplaceAll(t.label(), ":", "#58;"))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,148 @@
+package governance
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 分布式限流、共享熔断状态和配额统计使用的存储，可实现为Redis、etcd或内存存储
+type Store interface {
+	// 获取key的值，key不存在或已过期时返回false
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// 设置key的值，ttl为0表示不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// 将key的值加delta并返回结果，key不存在时从0开始并按ttl设置过期时间
+	Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// 监听key的变化，ctx结束前每次变化时调用fn，后端能感知key被删除或过期时以ok为false调用
+	Watch(ctx context.Context, key string, fn func(value []byte, ok bool)) error
+}
+
+// 内存存储中的值
+type storeEntry struct {
+	value  []byte
+	expire time.Time // 过期时间，零值表示不过期
+}
+
+// 内存存储的监听者
+type storeWatcher func(value []byte, ok bool)
+
+// 基于内存的存储，仅在单个进程内共享，用于单机部署和测试
+type memoryStore struct {
+	sync.Mutex
+	entries  map[string]*storeEntry
+	watchers map[string][]*storeWatcher
+}
+
+// 创建内存存储
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries:  make(map[string]*storeEntry),
+		watchers: make(map[string][]*storeWatcher),
+	}
+}
+
+// 获取未过期的值，调用方需持有锁
+func (s *memoryStore) get(key string, now time.Time) (*storeEntry, bool) {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expire.IsZero() && !now.Before(e.expire) {
+		delete(s.entries, key)
+		return nil, false
+	}
+
+	return e, true
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	e, ok := s.get(key, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+
+	return append([]byte(nil), e.value...), true, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	e := &storeEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expire = time.Now().Add(ttl)
+	}
+
+	s.Lock()
+	s.entries[key] = e
+	s.Unlock()
+	s.notify(key, value)
+
+	return nil
+}
+
+func (s *memoryStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	s.Lock()
+	now := time.Now()
+	e, ok := s.get(key, now)
+	if !ok {
+		e = &storeEntry{}
+		if ttl > 0 {
+			e.expire = now.Add(ttl)
+		}
+		s.entries[key] = e
+	}
+
+	var n int64
+	if len(e.value) > 0 {
+		var err error
+		if n, err = strconv.ParseInt(string(e.value), 10, 64); err != nil {
+			s.Unlock()
+			return 0, err
+		}
+	}
+	n += delta
+	e.value = []byte(strconv.FormatInt(n, 10))
+	value := e.value
+	s.Unlock()
+	s.notify(key, value)
+
+	return n, nil
+}
+
+func (s *memoryStore) Watch(ctx context.Context, key string, fn func(value []byte, ok bool)) error {
+	w := storeWatcher(fn)
+	s.Lock()
+	s.watchers[key] = append(s.watchers[key], &w)
+	s.Unlock()
+
+	<-ctx.Done()
+
+	s.Lock()
+	watchers := s.watchers[key]
+	for i, v := range watchers {
+		if v == &w {
+			s.watchers[key] = append(watchers[:i:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(s.watchers[key]) == 0 {
+		delete(s.watchers, key)
+	}
+	s.Unlock()
+
+	return ctx.Err()
+}
+
+// 通知key的监听者，不持有锁调用
+func (s *memoryStore) notify(key string, value []byte) {
+	s.Lock()
+	watchers := append([]*storeWatcher(nil), s.watchers[key]...)
+	s.Unlock()
+
+	for _, fn := range watchers {
+		(*fn)(append([]byte(nil), value...), true)
+	}
+}
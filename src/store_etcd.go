@@ -0,0 +1,80 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// etcd事务冲突重试次数耗尽时返回的错误
+var ErrStoreConflict = errors.New("governance: store update conflicted too many times")
+
+// Incr在事务冲突时的最大重试次数
+const etcdIncrRetries = 16
+
+// EtcdStore依赖的etcd客户端操作，可由clientv3适配实现，ttl通过lease实现
+type EtcdClient interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// key的当前值为old时写入value，exists为false表示要求key不存在，返回是否写入成功
+	CompareAndSwap(ctx context.Context, key string, old []byte, exists bool, value []byte, ttl time.Duration) (bool, error)
+	// 监听key，ctx结束前每次变化时调用fn
+	Watch(ctx context.Context, key string, fn func(value []byte, ok bool)) error
+}
+
+// 基于etcd的存储
+type EtcdStore struct {
+	Client EtcdClient
+}
+
+// 创建etcd存储
+func NewEtcdStore(client EtcdClient) *EtcdStore {
+	return &EtcdStore{Client: client}
+}
+
+func (s *EtcdStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return s.Client.Get(ctx, key)
+}
+
+func (s *EtcdStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.Client.Put(ctx, key, value, ttl)
+}
+
+// 通过比较并交换实现原子自增，已存在的key保留原有的lease
+func (s *EtcdStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	for i := 0; i < etcdIncrRetries; i++ {
+		old, exists, err := s.Client.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+
+		var n int64
+		if exists {
+			if n, err = strconv.ParseInt(string(old), 10, 64); err != nil {
+				return 0, err
+			}
+		}
+		n += delta
+
+		keyTTL := ttl
+		if exists {
+			keyTTL = 0
+		}
+		ok, err := s.Client.CompareAndSwap(ctx, key, old, exists, []byte(strconv.FormatInt(n, 10)), keyTTL)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return n, nil
+		}
+	}
+
+	return 0, ErrStoreConflict
+}
+
+func (s *EtcdStore) Watch(ctx context.Context, key string, fn func(value []byte, ok bool)) error {
+	return s.Client.Watch(ctx, key, fn)
+}
+
+var _ Store = (*EtcdStore)(nil)
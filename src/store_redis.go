@@ -0,0 +1,82 @@
+package governance
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RedisStore依赖的Redis客户端操作，可由go-redis等客户端适配实现
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// 执行Lua脚本，Incr通过脚本原子地完成自增和设置过期时间
+	RedisScripter
+	Publish(ctx context.Context, channel string, message []byte) error
+	// 订阅channel，ctx结束前每收到一条消息调用fn
+	Subscribe(ctx context.Context, channel string, fn func(message []byte)) error
+}
+
+// 自增计数，key没有过期时间时设置过期时间，避免进程在两步之间崩溃留下永不过期的计数
+const storeIncrScript = `
+local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return n`
+
+// 基于Redis的存储，变更通过发布订阅通知监听者
+type RedisStore struct {
+	Client RedisClient
+	Prefix string // 变更通知的channel前缀，channel为Prefix+key
+}
+
+// 创建Redis存储
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{
+		Client: client,
+		Prefix: prefix,
+	}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return s.Client.Get(ctx, key)
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.Client.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	return s.Client.Publish(ctx, s.Prefix+key, value)
+}
+
+func (s *RedisStore) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	var ms int64
+	if ttl > 0 {
+		ms = max(1, ttl.Milliseconds())
+	}
+	v, err := s.Client.Eval(ctx, storeIncrScript, []string{key}, delta, ms)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return 0, fmt.Errorf("governance: unexpected incr script result %v", v)
+	}
+
+	return n, nil
+}
+
+// 收到变更通知后读取最新值，避免通知乱序时回调旧值
+func (s *RedisStore) Watch(ctx context.Context, key string, fn func(value []byte, ok bool)) error {
+	return s.Client.Subscribe(ctx, s.Prefix+key, func([]byte) {
+		value, ok, err := s.Client.Get(ctx, key)
+		if err != nil {
+			return
+		}
+		fn(value, ok)
+	})
+}
+
+var _ Store = (*RedisStore)(nil)
@@ -0,0 +1,35 @@
+package governance
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type evalRecorder struct {
+	RedisClient
+	keys []string
+	args []interface{}
+}
+
+func (c *evalRecorder) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	c.keys, c.args = keys, args
+	return int64(3), nil
+}
+
+// 自增和设置过期时间在同一个脚本中执行，不足1毫秒的ttl按1毫秒处理
+func TestRedisStoreIncrSingleScript(t *testing.T) {
+	for _, tc := range []struct {
+		ttl time.Duration
+		ms  int64
+	}{{0, 0}, {time.Microsecond, 1}, {time.Second, 1000}} {
+		c := &evalRecorder{}
+		n, err := NewRedisStore(c, "gov:").Incr(context.Background(), "k", 2, tc.ttl)
+		if err != nil || n != 3 {
+			t.Fatalf("Incr = %d, %v", n, err)
+		}
+		if len(c.keys) != 1 || c.keys[0] != "k" || c.args[0] != int64(2) || c.args[1] != tc.ms {
+			t.Errorf("ttl %v: keys %v args %v", tc.ttl, c.keys, c.args)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package governance
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 并发流数达到上限时返回的错误
+var ErrTooManyStreams = errors.New("governance: too many concurrent streams")
+
+// 长连接流治理配置
+type StreamConfig struct {
+	MaxStreams   int     `toml:"max_streams"`   // 每个rpc资源的最大并发流数，0表示不限制
+	MessageRate  float64 `toml:"message_rate"`  // 每个流每秒允许的消息数，0表示不限制
+	MessageBurst int     `toml:"message_burst"` // 每个流允许突发的消息数，为0时使用max(1, ceil(MessageRate))
+}
+
+// 长连接流治理：建流失败计入熔断，限制每个rpc资源的并发流数，并对流内消息限流
+type StreamGovernor struct {
+	Config  *StreamConfig
+	Breaker *Breaker
+
+	sync.Mutex
+	streams map[string]int
+}
+
+// 创建长连接流治理
+func NewStreamGovernor(config *StreamConfig, breaker *Breaker) *StreamGovernor {
+	return &StreamGovernor{
+		Config:  config,
+		Breaker: breaker,
+		streams: make(map[string]int),
+	}
+}
+
+// 已建立的流
+type Stream struct {
+	governor *StreamGovernor
+	resource string
+	limiter  *Limiter
+	once     sync.Once
+}
+
+// 为rpc资源r建立流，open的失败计入熔断，成功后需调用Close释放并发名额
+func (g *StreamGovernor) Open(ctx context.Context, r string, open func(ctx context.Context) error) (*Stream, error) {
+	if !g.acquire(r) {
+		return nil, ErrTooManyStreams
+	}
+
+	if err := g.Breaker.Execute(ctx, r, open); err != nil {
+		g.release(r)
+		return nil, err
+	}
+
+	s := &Stream{governor: g, resource: r}
+	if g.Config.MessageRate > 0 {
+		s.limiter = NewLimiter(&LimiterConfig{QPS: g.Config.MessageRate, Burst: defaultBurst(g.Config.MessageRate, g.Config.MessageBurst)})
+	}
+
+	return s, nil
+}
+
+// 判断流内是否允许发送或处理一条消息
+func (s *Stream) AllowMessage() bool {
+	return s.limiter == nil || s.limiter.Allow(s.resource)
+}
+
+// 流结束时调用，err为流异常结束的错误，非空时计入熔断
+func (s *Stream) Close(err error) {
+	s.once.Do(func() {
+		s.governor.release(s.resource)
+		if err != nil {
+			s.governor.Breaker.setFail(s.resource, err)
+		}
+	})
+}
+
+// rpc资源r当前的并发流数
+func (g *StreamGovernor) Streams(r string) int {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.streams[r]
+}
+
+func (g *StreamGovernor) acquire(r string) bool {
+	g.Lock()
+	defer g.Unlock()
+
+	if g.Config.MaxStreams > 0 && g.streams[r] >= g.Config.MaxStreams {
+		return false
+	}
+	g.streams[r]++
+
+	return true
+}
+
+func (g *StreamGovernor) release(r string) {
+	g.Lock()
+	defer g.Unlock()
+
+	g.streams[r]--
+	if g.streams[r] <= 0 {
+		delete(g.streams, r)
+	}
+}
@@ -0,0 +1,26 @@
+package governance
+
+import (
+	"context"
+	"testing"
+)
+
+// 未设置消息突发数时按速率取默认值，而不是拒绝所有消息
+func TestStreamDefaultMessageBurst(t *testing.T) {
+	config := DefaultConfig()
+	g := NewStreamGovernor(&StreamConfig{MessageRate: 2}, newBreaker(&config))
+	s, err := g.Open(context.Background(), "svc", func(context.Context) error { return nil })
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer s.Close(nil)
+
+	for i := 0; i < 2; i++ {
+		if !s.AllowMessage() {
+			t.Fatalf("message %d rejected, want burst of 2", i)
+		}
+	}
+	if s.AllowMessage() {
+		t.Fatal("message 2 allowed, want burst of 2")
+	}
+}
@@ -0,0 +1,29 @@
+package governance
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// 确定性子集划分，客户端clientID只连接backends中的size个实例，同一轮的客户端均匀覆盖所有实例
+func Subset(backends []string, clientID int, size int) []string {
+	if size <= 0 || size >= len(backends) {
+		return backends
+	}
+
+	shuffled := make([]string, len(backends))
+	copy(shuffled, backends)
+	sort.Strings(shuffled)
+
+	// 每轮将实例划分为subsetCount个子集，同一轮的客户端使用相同的打乱顺序
+	subsetCount := len(shuffled) / size
+	round := clientID / subsetCount
+	r := rand.New(rand.NewSource(int64(round)))
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	start := (clientID % subsetCount) * size
+
+	return shuffled[start : start+size]
+}
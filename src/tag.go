@@ -0,0 +1,15 @@
+package governance
+
+// 标签过滤条件，rpc资源需包含所有指定的标签值
+type TagFilter map[string]string
+
+// 判断标签tags是否匹配过滤条件，过滤条件为空时匹配所有标签
+func (f TagFilter) Match(tags map[string]string) bool {
+	for k, v := range f {
+		if tags[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,207 @@
+package governance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 调用超过看门狗超时时间仍未返回时计入失败的错误
+var ErrCallHung = errors.New("governance: call exceeded watchdog timeout without returning")
+
+// 超时未返回的调用
+type HungCall struct {
+	Resource string
+	Start    time.Time
+	Elapsed  time.Duration
+	Stack    string // 执行调用的goroutine的调用栈，未开启Watchdog.CaptureStacks时为空
+}
+
+// 看门狗监视中的调用
+type watchedCall struct {
+	resource string
+	start    time.Time
+	timeout  time.Duration
+	gid      uint64 // 执行调用的goroutine的id，未开启CaptureStacks时为0
+	hung     int32  // 是否已被判定为超时未返回
+}
+
+// 复用监视记录，避免每次调用分配
+var watchedCallPool = sync.Pool{New: func() interface{} { return new(watchedCall) }}
+
+// 调用看门狗，发现超过WatchdogTimeout仍未返回的调用时记录调用栈并计为失败
+// 被判定超时的调用之后返回时不再重复计入调用结果
+type Watchdog struct {
+	Breaker *Breaker
+	OnHung  func(call HungCall) // 发现超时未返回的调用时调用，为空时只记录日志并计为失败
+	// 是否记录执行调用的goroutine，用于输出超时未返回调用的调用栈，每次调用需读取一次当前goroutine的调用栈，耗时数微秒
+	CaptureStacks bool
+
+	sync.Mutex
+	calls map[*watchedCall]struct{}
+	hung  int64 // 累计超时未返回的调用数
+}
+
+// 创建看门狗，需设置为breaker.Watchdog并调用Run
+func NewWatchdog(breaker *Breaker, onHung func(call HungCall)) *Watchdog {
+	return &Watchdog{
+		Breaker: breaker,
+		OnHung:  onHung,
+		calls:   make(map[*watchedCall]struct{}),
+	}
+}
+
+// 开始监视rpc资源r的一次调用，未配置超时时返回空
+func (w *Watchdog) watch(r string) *watchedCall {
+	if w == nil {
+		return nil
+	}
+	w.Breaker.RLock()
+	timeout := w.Breaker.configOf(r).WatchdogTimeout
+	w.Breaker.RUnlock()
+	if timeout <= 0 {
+		return nil
+	}
+
+	c := watchedCallPool.Get().(*watchedCall)
+	*c = watchedCall{
+		resource: r,
+		start:    time.Now(),
+		timeout:  time.Duration(timeout) * time.Millisecond,
+	}
+	if w.CaptureStacks {
+		c.gid = goroutineID()
+	}
+
+	w.Lock()
+	w.calls[c] = struct{}{}
+	w.Unlock()
+
+	return c
+}
+
+// 结束监视，调用已被判定为超时未返回时返回true
+func (w *Watchdog) done(c *watchedCall) bool {
+	if c == nil {
+		return false
+	}
+
+	w.Lock()
+	delete(w.calls, c)
+	hung := atomic.LoadInt32(&c.hung) == 1
+	w.Unlock()
+	watchedCallPool.Put(c)
+
+	return hung
+}
+
+// 检查超时未返回的调用
+func (w *Watchdog) Check() {
+	now := time.Now()
+	// 监视记录在调用结束后被复用，持有锁时复制需要的字段
+	var hung []watchedCall
+	w.Lock()
+	for c := range w.calls {
+		if now.Sub(c.start) > c.timeout && atomic.CompareAndSwapInt32(&c.hung, 0, 1) {
+			hung = append(hung, watchedCall{resource: c.resource, start: c.start, gid: c.gid})
+		}
+	}
+	w.Unlock()
+	if len(hung) == 0 {
+		return
+	}
+
+	var stacks []byte
+	if w.CaptureStacks {
+		stacks = allStacks()
+	}
+	for _, c := range hung {
+		atomic.AddInt64(&w.hung, 1)
+		w.Breaker.setFail(c.resource, ErrCallHung)
+		var stack string
+		if c.gid != 0 {
+			stack = goroutineStack(stacks, c.gid)
+		}
+		logDecision(context.Background(), LogWatchdog, slog.LevelWarn, "call hung past watchdog timeout", c.resource, "hung", "fail",
+			slog.Duration("elapsed", now.Sub(c.start)), slog.String("stack", stack))
+		if w.OnHung != nil {
+			w.OnHung(HungCall{
+				Resource: c.resource,
+				Start:    c.start,
+				Elapsed:  now.Sub(c.start),
+				Stack:    stack,
+			})
+		}
+	}
+}
+
+// 累计超时未返回的调用数
+func (w *Watchdog) Hung() int64 {
+	return atomic.LoadInt64(&w.hung)
+}
+
+// 定时检查超时未返回的调用，ctx结束时返回
+func (w *Watchdog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check()
+		}
+	}
+}
+
+// 所有goroutine的调用栈
+func allStacks() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// 解析goroutine id时读取调用栈首行的缓冲区，runtime.Stack会使缓冲区逃逸到堆上，复用以避免每次调用分配
+var gidBufPool = sync.Pool{New: func() interface{} { return new([64]byte) }}
+
+// 当前goroutine的id，从调用栈首行"goroutine 12 [running]:"中解析
+func goroutineID() uint64 {
+	buf := gidBufPool.Get().(*[64]byte)
+	defer gidBufPool.Put(buf)
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+
+	var id uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+
+	return id
+}
+
+// 从所有goroutine的调用栈中取出id为gid的一段
+func goroutineStack(stacks []byte, gid uint64) string {
+	prefix := []byte("goroutine " + strconv.FormatUint(gid, 10) + " ")
+	for _, s := range bytes.Split(stacks, []byte("\n\n")) {
+		if bytes.HasPrefix(s, prefix) {
+			return string(s)
+		}
+	}
+
+	return ""
+}
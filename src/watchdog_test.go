@@ -0,0 +1,41 @@
+package governance
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 开启CaptureStacks时超时未返回的调用带有执行调用的goroutine的调用栈，否则不读取调用栈
+func TestWatchdogCaptureStacks(t *testing.T) {
+	for _, capture := range []bool{false, true} {
+		config := DefaultConfig()
+		config.WatchdogTimeout = 5
+		breaker := newBreaker(&config)
+		var calls []HungCall
+		breaker.Watchdog = NewWatchdog(breaker, func(call HungCall) { calls = append(calls, call) })
+		breaker.Watchdog.CaptureStacks = capture
+
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			breaker.Execute(context.Background(), "r", func(ctx context.Context) error {
+				<-release
+				return nil
+			})
+		}()
+		time.Sleep(20 * time.Millisecond)
+		breaker.Watchdog.Check()
+		close(release)
+		<-done
+
+		if len(calls) != 1 {
+			t.Fatalf("capture=%v: %d hung calls, want 1", capture, len(calls))
+		}
+		if got := strings.Contains(calls[0].Stack, "TestWatchdogCaptureStacks"); got != capture {
+			t.Fatalf("capture=%v: stack contains caller = %v\n%s", capture, got, calls[0].Stack)
+		}
+	}
+}
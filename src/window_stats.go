@@ -0,0 +1,156 @@
+package governance
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// 滑动窗口的桶数，每个桶1秒，窗口最长为1分钟
+	windowBuckets = 60
+	// 耗时分布的桶数，桶i的上限为100µs*1.25^i，覆盖约100µs到130s
+	latencyBuckets = 64
+	latencyBase    = 100 * time.Microsecond
+	latencyGrowth  = 1.25
+)
+
+// 1秒内的调用统计
+type windowBucket struct {
+	second   int64 // 桶对应的unix秒
+	requests int64
+	failures int64
+	latency  [latencyBuckets]int64
+}
+
+// rpc资源最近1分钟的滑动窗口统计
+type windowStats struct {
+	sync.Mutex
+	buckets [windowBuckets]windowBucket
+}
+
+// 耗时d所在的分布桶
+func latencyBucket(d time.Duration) int {
+	if d <= latencyBase {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(latencyBase)) / math.Log(latencyGrowth)))
+	if i >= latencyBuckets {
+		return latencyBuckets - 1
+	}
+
+	return i
+}
+
+// 分布桶i的耗时上限
+func latencyUpper(i int) time.Duration {
+	return time.Duration(float64(latencyBase) * math.Pow(latencyGrowth, float64(i)))
+}
+
+// 记录一次调用
+func (w *windowStats) record(now time.Time, d time.Duration, failed bool) {
+	sec := now.Unix()
+	w.Lock()
+	defer w.Unlock()
+
+	b := &w.buckets[sec%windowBuckets]
+	if b.second != sec {
+		*b = windowBucket{second: sec}
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	b.latency[latencyBucket(d)]++
+}
+
+// 汇总最近window内的桶
+func (w *windowStats) sum(now time.Time, window time.Duration) windowBucket {
+	n := int64(window / time.Second)
+	if n <= 0 {
+		n = 1
+	}
+	if n > windowBuckets {
+		n = windowBuckets
+	}
+	sec := now.Unix()
+
+	var total windowBucket
+	w.Lock()
+	defer w.Unlock()
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if b.second <= sec-n || b.second > sec {
+			continue
+		}
+		total.requests += b.requests
+		total.failures += b.failures
+		for j, c := range b.latency {
+			total.latency[j] += c
+		}
+	}
+
+	return total
+}
+
+// rpc资源r的滑动窗口统计，不存在时创建
+func (breaker *Breaker) windowOf(r string) *windowStats {
+	if v, ok := breaker.windows.Load(r); ok {
+		return v.(*windowStats)
+	}
+	v, _ := breaker.windows.LoadOrStore(r, &windowStats{})
+
+	return v.(*windowStats)
+}
+
+// rpc资源r最近window内的调用数，window最长为1分钟，按秒取整
+func (breaker *Breaker) Requests(r string, window time.Duration) int64 {
+	v, ok := breaker.windows.Load(r)
+	if !ok {
+		return 0
+	}
+
+	return v.(*windowStats).sum(breaker.now(), window).requests
+}
+
+// rpc资源r最近window内的错误率，没有调用时为0，可用于在依赖降级时关闭昂贵的功能
+func (breaker *Breaker) ErrorRate(r string, window time.Duration) float64 {
+	v, ok := breaker.windows.Load(r)
+	if !ok {
+		return 0
+	}
+	s := v.(*windowStats).sum(breaker.now(), window)
+	if s.requests == 0 {
+		return 0
+	}
+
+	return float64(s.failures) / float64(s.requests)
+}
+
+// rpc资源r最近window内调用耗时的q分位数，q取值0~1，按分布桶的上限估算，误差不超过25%
+func (breaker *Breaker) Percentile(r string, window time.Duration, q float64) time.Duration {
+	v, ok := breaker.windows.Load(r)
+	if !ok {
+		return 0
+	}
+	s := v.(*windowStats).sum(breaker.now(), window)
+	if s.requests == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q * float64(s.requests)))
+	var seen int64
+	for i, c := range s.latency {
+		seen += c
+		if seen >= rank && c > 0 {
+			return latencyUpper(i)
+		}
+	}
+
+	return latencyUpper(latencyBuckets - 1)
+}
+
+// rpc资源r最近window内调用耗时的99分位数
+func (breaker *Breaker) P99(r string, window time.Duration) time.Duration {
+	return breaker.Percentile(r, window, 0.99)
+}
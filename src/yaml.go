@@ -0,0 +1,319 @@
+package governance
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// YAML的一个非空行
+type yamlLine struct {
+	num    int // 行号，从1开始
+	indent int
+	text   string // 去掉缩进和注释后的内容
+}
+
+// 解析YAML的子集，结果的类型与encoding/json解码到interface{}一致，用于以YAML编写的规则集
+// 支持块映射、块序列、注释、单双引号字符串、数字、布尔值、null以及单行的流式序列和映射，不支持锚点、标签、多文档和多行字符串
+func decodeYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("governance: yaml line %d: tabs are not allowed for indentation", i+1)
+		}
+		text = strings.TrimRight(stripYAMLComment(text), " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		lines = append(lines, yamlLine{num: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	v, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+
+	return v, nil
+}
+
+// 去掉行内的注释，引号内的#不是注释
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+
+	return text
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	num := p.lines[len(p.lines)-1].num
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	}
+
+	return fmt.Errorf("governance: yaml line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+// 解析缩进为indent的块，按首行判断是序列还是映射
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+
+	return p.mapping(indent)
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// 解析缩进为indent的块映射
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if isYAMLSeqItem(line.text) {
+			return nil, p.errorf("unexpected sequence item in mapping")
+		}
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, p.errorf("expected key: value")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+
+		var v interface{}
+		var err error
+		switch {
+		case rest != "":
+			v, err = parseYAMLValue(rest)
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			v, err = p.block(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].text):
+			// 序列可以与所属的key缩进相同
+			v, err = p.sequence(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+
+	return m, nil
+}
+
+// 解析缩进为indent的块序列
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	seq := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if rest == "" {
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				seq = append(seq, nil)
+				continue
+			}
+			v, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+
+		if _, _, ok := splitYAMLKey(rest); ok && !strings.HasPrefix(rest, "{") {
+			// "- key: value"开始一个映射，映射的缩进为key所在的列
+			p.lines[p.pos] = yamlLine{num: line.num, indent: indent + len(line.text) - len(rest), text: rest}
+			v, err := p.mapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			continue
+		}
+
+		v, err := parseYAMLValue(rest)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		seq = append(seq, v)
+		p.pos++
+	}
+
+	return seq, nil
+}
+
+// 将"key: value"拆分为key和value，value可为空
+func splitYAMLKey(text string) (string, string, bool) {
+	var key string
+	rest := text
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingYAMLQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		k, err := parseYAMLScalar(text[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		key, rest = k.(string), text[end+1:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimLeft(rest[1:], " "), rest == ":" || rest[1] == ' '
+	}
+
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false
+		}
+		i = len(text) - 1
+	}
+	key = strings.TrimRight(text[:i], " ")
+	if key == "" {
+		return "", "", false
+	}
+
+	return key, strings.TrimLeft(text[i+1:], " "), true
+}
+
+// 以引号开始的text中闭合引号的位置，没有时返回-1
+func closingYAMLQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+
+	return -1
+}
+
+// 解析值，可以是标量或单行的流式序列和映射
+func parseYAMLValue(text string) (interface{}, error) {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		// 流式集合的语法与JSON相近，未加引号的字符串逐项转换为JSON字符串后解码
+		var v interface{}
+		if err := json.Unmarshal([]byte(quoteYAMLFlow(text)), &v); err != nil {
+			return nil, fmt.Errorf("invalid flow collection %s", text)
+		}
+		return v, nil
+	}
+
+	return parseYAMLScalar(text)
+}
+
+// 将流式集合中的标量转换为JSON的写法
+func quoteYAMLFlow(text string) string {
+	var b strings.Builder
+	item := func(s string) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return
+		}
+		v, err := parseYAMLScalar(s)
+		if err != nil {
+			b.WriteString(s)
+			return
+		}
+		data, _ := json.Marshal(v)
+		b.Write(data)
+	}
+
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '"', '\'':
+			if end := closingYAMLQuote(text[i:]); end > 0 {
+				i += end
+			}
+		case '[', ']', '{', '}', ',', ':':
+			if c == ':' && i+1 < len(text) && text[i+1] != ' ' {
+				continue
+			}
+			item(text[start:i])
+			b.WriteByte(c)
+			start = i + 1
+		}
+	}
+	item(text[start:])
+
+	return b.String()
+}
+
+// 解析标量，结果为string、float64、bool或nil
+func parseYAMLScalar(text string) (interface{}, error) {
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+
+	switch text[0] {
+	case '"':
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double-quoted string %s", text)
+		}
+		return s, nil
+	case '\'':
+		if len(text) < 2 || text[len(text)-1] != '\'' {
+			return nil, fmt.Errorf("invalid single-quoted string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	// 只按JSON数字的写法解析数字，30s、0x10等按字符串处理
+	var n float64
+	if json.Unmarshal([]byte(text), &n) == nil {
+		return n, nil
+	}
+
+	return text, nil
+}
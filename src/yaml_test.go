@@ -0,0 +1,69 @@
+package governance
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	src := `# rules
+breakers:
+  user-service:
+    fail_threshold: 5   # comment
+    open_timeout: "30s"
+    tags: {team: core, "tier": 1}
+    dry_run: false
+  "svc/Get":
+    max_open_timeout: 2m
+list:
+- a
+- b: 1
+  c: 'it''s'
+-
+  - x
+flow: [1, two, "3"]
+empty:
+url: http://x#y
+`
+	want := `{"breakers":{"svc/Get":{"max_open_timeout":"2m"},"user-service":{"dry_run":false,"fail_threshold":5,"open_timeout":"30s","tags":{"team":"core","tier":1}}},"empty":null,"flow":[1,"two","3"],"list":["a",{"b":1,"c":"it's"},["x"]],"url":"http://x#y"}`
+
+	v, err := decodeYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(v)
+	if string(got) != want {
+		t.Errorf("decodeYAML =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDecodeYAMLErrors(t *testing.T) {
+	tests := map[string]string{
+		"a: 1\n  b: 2":  "line 2: unexpected indentation",
+		"a: 1\na: 2":    `line 2: duplicate key "a"`,
+		"a:\n\t- x":     "line 2: tabs are not allowed",
+		"a: [1, 2":      "line 1: invalid flow collection",
+		"a: \"unclosed": "line 1: invalid double-quoted string",
+	}
+	for src, want := range tests {
+		if _, err := decodeYAML([]byte(src)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("decodeYAML(%q) error = %v, want %q", src, err, want)
+		}
+	}
+}
+
+func TestValidateRulesYAML(t *testing.T) {
+	rules, errs := ValidateRulesYAML([]byte("breakers:\n  user-service:\n    fail_threshold: 3\n    open_timeout: 30s\n"))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if c := rules.Breakers["user-service"]; c.FailThreshold != 3 || c.OpenTimeout.String() != "30s" {
+		t.Errorf("config = %+v", c)
+	}
+
+	_, errs = ValidateRulesYAML([]byte("breakers:\n  user-service:\n    fail_treshold: 3\n"))
+	if len(errs) != 1 || errs[0].Path != "breakers.user-service.fail_treshold" {
+		t.Errorf("errs = %v", errs)
+	}
+}
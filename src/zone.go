@@ -0,0 +1,199 @@
+package governance
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 探测到可用区zone的往返时延
+type RTTProber func(ctx context.Context, zone string) (time.Duration, error)
+
+// 往返时延的指数加权平均系数
+const rttAlpha = 0.3
+
+// 可用区的往返时延探测结果
+type ZoneRTT struct {
+	Zone      string
+	RTT       time.Duration // 往返时延的指数加权平均，本可用区为0
+	Reachable bool          // 最近一次探测是否成功
+	ProbedAt  time.Time
+}
+
+// 多可用区实例选择器，优先选择本可用区的实例，本可用区没有可用实例时按往返时延由近到远溢出到其他可用区
+// 探测失败或尚未探测的可用区排在最后
+type ZoneInstances struct {
+	Instances *StaticInstances
+	Local     string    // 本实例所在的可用区
+	Prober    RTTProber // 往返时延探测函数，为空时不探测，其他可用区按名称排序
+
+	sync.RWMutex
+	zoneOf map[string]string // 实例所在的可用区，key为ResourceKey{Service, Instance}编码
+	rtts   map[string]*ZoneRTT
+}
+
+// 创建多可用区实例选择器
+func NewZoneInstances(instances *StaticInstances, local string, prober RTTProber) *ZoneInstances {
+	return &ZoneInstances{
+		Instances: instances,
+		Local:     local,
+		Prober:    prober,
+		zoneOf:    make(map[string]string),
+		rtts:      make(map[string]*ZoneRTT),
+	}
+}
+
+// 设置服务service各实例所在的可用区，并更新实例列表
+func (z *ZoneInstances) SetZones(service string, zones map[string]string) {
+	instances := make([]string, 0, len(zones))
+	for inst := range zones {
+		instances = append(instances, inst)
+	}
+	sort.Strings(instances)
+
+	z.Lock()
+	for _, inst := range z.Instances.Instances(service) {
+		delete(z.zoneOf, ResourceKey{Service: service, Instance: inst}.Encode())
+	}
+	for inst, zone := range zones {
+		z.zoneOf[ResourceKey{Service: service, Instance: inst}.Encode()] = zone
+		if _, ok := z.rtts[zone]; !ok && zone != z.Local {
+			z.rtts[zone] = &ZoneRTT{Zone: zone}
+		}
+	}
+	z.Unlock()
+
+	z.Instances.SetInstances(service, instances)
+}
+
+// 按优先级排序的可用区，本可用区最先，其余按往返时延由近到远
+func (z *ZoneInstances) order(zones map[string]bool) []string {
+	z.RLock()
+	defer z.RUnlock()
+
+	ordered := make([]string, 0, len(zones))
+	for zone := range zones {
+		ordered = append(ordered, zone)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if (a == z.Local) != (b == z.Local) {
+			return a == z.Local
+		}
+		ra, rb := z.rtts[a], z.rtts[b]
+		okA, okB := ra != nil && ra.Reachable, rb != nil && rb.Reachable
+		if okA != okB {
+			return okA
+		}
+		if okA && ra.RTT != rb.RTT {
+			return ra.RTT < rb.RTT
+		}
+		return a < b
+	})
+
+	return ordered
+}
+
+// 按可用区优先级选择服务service不在exclude中的可用实例
+func (z *ZoneInstances) PickInstance(service string, exclude map[string]bool) (string, bool) {
+	instances := z.Instances.Instances(service)
+	zoneOf := make(map[string]string, len(instances))
+	zones := make(map[string]bool)
+	z.RLock()
+	for _, inst := range instances {
+		zone := z.zoneOf[ResourceKey{Service: service, Instance: inst}.Encode()]
+		zoneOf[inst] = zone
+		zones[zone] = true
+	}
+	z.RUnlock()
+
+	for _, zone := range z.order(zones) {
+		// 排除其他可用区的实例，只在当前可用区内选择
+		skip := make(map[string]bool, len(instances))
+		for inst, instZone := range zoneOf {
+			if exclude[inst] || instZone != zone {
+				skip[inst] = true
+			}
+		}
+		if inst, ok := z.Instances.PickInstance(service, skip); ok {
+			return inst, true
+		}
+	}
+
+	return "", false
+}
+
+// 探测所有其他可用区的往返时延
+func (z *ZoneInstances) Probe(ctx context.Context) {
+	if z.Prober == nil {
+		return
+	}
+
+	z.RLock()
+	zones := make([]string, 0, len(z.rtts))
+	for zone := range z.rtts {
+		zones = append(zones, zone)
+	}
+	z.RUnlock()
+
+	for _, zone := range zones {
+		rtt, err := z.Prober(ctx, zone)
+		now := time.Now()
+
+		z.Lock()
+		if s, ok := z.rtts[zone]; ok {
+			s.ProbedAt = now
+			s.Reachable = err == nil
+			if err == nil {
+				if s.RTT == 0 {
+					s.RTT = rtt
+				} else {
+					s.RTT = time.Duration(rttAlpha*float64(rtt) + (1-rttAlpha)*float64(s.RTT))
+				}
+			}
+		}
+		z.Unlock()
+	}
+}
+
+// 定时探测其他可用区的往返时延，ctx结束时返回
+func (z *ZoneInstances) Run(ctx context.Context, interval time.Duration) {
+	z.Probe(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			z.Probe(ctx)
+		}
+	}
+}
+
+// 各可用区的往返时延，按可用区优先级排序
+func (z *ZoneInstances) RTTs() []ZoneRTT {
+	z.RLock()
+	zones := map[string]bool{z.Local: true}
+	for zone := range z.rtts {
+		zones[zone] = true
+	}
+	z.RUnlock()
+
+	ordered := z.order(zones)
+	z.RLock()
+	defer z.RUnlock()
+	rtts := make([]ZoneRTT, 0, len(ordered))
+	for _, zone := range ordered {
+		if s, ok := z.rtts[zone]; ok {
+			rtts = append(rtts, *s)
+		} else {
+			rtts = append(rtts, ZoneRTT{Zone: zone, Reachable: true})
+		}
+	}
+
+	return rtts
+}